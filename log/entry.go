@@ -1,7 +1,6 @@
 package logger
 
 import (
-	"fmt"
 	"io"
 	"runtime"
 	"strconv"
@@ -92,16 +91,39 @@ func (e *Entry) Send() error {
 
 func (e *Entry) Str(key, val string) *Entry {
 	if e == nil {
-		fmt.Println("aa")
 		return e
 	}
 	e.buf = e.appendString(e.appendKey(e.buf, key), val)
 	return e
 }
 
+const hex = "0123456789abcdef"
+
+// json string, quotes, backslashes and control characters are escaped
 func (e *Entry) appendString(dst []byte, s string) []byte {
 	dst = append(dst, '"')
-	dst = append(dst, s...)
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+		dst = append(dst, s[start:i]...)
+		switch c {
+		case '"', '\\':
+			dst = append(dst, '\\', c)
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		case '\t':
+			dst = append(dst, '\\', 't')
+		default:
+			dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+		}
+		start = i + 1
+	}
+	dst = append(dst, s[start:]...)
 	return append(dst, '"')
 }
 
@@ -156,7 +178,7 @@ func (e *Entry) appendStrings(dst []byte, vals []string) []byte {
 	dst = append(dst, '[')
 	dst = e.appendString(dst, vals[0])
 	if len(vals) > 1 {
-		for _, val := range vals {
+		for _, val := range vals[1:] {
 			dst = e.appendString(append(dst, ','), val)
 		}
 	}
@@ -173,9 +195,7 @@ func (e *Entry) Bytes(key string, val []byte) *Entry {
 }
 
 func (e *Entry) appendBytes(dst []byte, val []byte) []byte {
-	dst = append(dst, '"')
-	dst = append(dst, val...)
-	return append(dst, '"')
+	return e.appendString(dst, string(val))
 }
 
 func (e *Entry) Err(key string, val error) *Entry {
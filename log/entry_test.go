@@ -0,0 +1,55 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestEntryEscaping(t *testing.T) {
+	tests := []string{
+		"plain",
+		"line\nbreak\r\n",
+		`"quoted" \back\slash`,
+		"tab\there",
+		"control \x00\x01\x1f\x7f bytes",
+		"unicode é 日本",
+		"",
+	}
+	for _, s := range tests {
+		var buf bytes.Buffer
+		if err := New(&buf).ERROR().Str("key \"\n", s).Strs("list", []string{s, s}).Bytes("bytes", []byte(s)).Msg(s); err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Errorf("%q: invalid json %s: %v", s, buf.Bytes(), err)
+			continue
+		}
+		if got["message"] != s || got["key \"\n"] != s || got["bytes"] != s {
+			t.Errorf("%q: got %v", s, got)
+		}
+		if list, _ := got["list"].([]interface{}); len(list) != 2 || list[0] != s {
+			t.Errorf("%q: got list %v", s, got["list"])
+		}
+	}
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf)
+	l.level = WarnLevel
+	_ = l.DEBUG().Str("a", "b").Msg("debug")
+	_ = l.INFO().Msg("info")
+	if buf.Len() != 0 {
+		t.Errorf("entries below the level written: %s", buf.Bytes())
+	}
+	_ = l.ERROR().Msg("error")
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["level"] != "error" || got["message"] != "error" {
+		t.Errorf("got %v", got)
+	}
+}
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"context"
 	"io"
 	"os"
 	"sync"
@@ -16,7 +17,6 @@ import (
 	bs = append(bs, '}')
 	fmt.Println(string(bs))
 */
-// todo: 替换专义字符
 
 type Level uint8
 
@@ -52,13 +52,19 @@ func (l Level) String() string {
 }
 
 type Logger struct {
-	mu    *sync.Mutex
-	out   io.Writer
-	level Level
+	mu     *sync.Mutex
+	out    io.Writer
+	level  Level
+	fields []string // key, value pairs added to every entry
 }
 
 var std = New(os.Stdout)
 
+// the logger behind the package level functions
+func Default() *Logger {
+	return std
+}
+
 func New(out io.Writer) *Logger {
 	return &Logger{out: out, mu: new(sync.Mutex)}
 }
@@ -69,34 +75,64 @@ func (l *Logger) SetOutput(out io.Writer) {
 	l.out = out
 }
 
-func (l *Logger) newEntry() *Entry {
-	return newEntry(l.out, l.level)
+// child logger adding key to every entry
+func (l *Logger) With(key, value string) *Logger {
+	fields := make([]string, 0, len(l.fields)+2)
+	fields = append(fields, l.fields...)
+	return &Logger{mu: l.mu, out: l.out, level: l.level, fields: append(fields, key, value)}
+}
+
+type ctxKey struct{}
+
+// context carrying l, see Ctx
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// logger stored by WithContext, the default logger if there is none
+func Ctx(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+		return l
+	}
+	return std
+}
+
+// entries below the logger level are nil and discard their fields
+func (l *Logger) newEntry(level Level) *Entry {
+	if level < l.level {
+		return nil
+	}
+	e := newEntry(l.out, level)
+	for i := 0; i+1 < len(l.fields); i += 2 {
+		e.Str(l.fields[i], l.fields[i+1])
+	}
+	return e
 }
 
 func (l *Logger) DEBUG() *Entry {
-	return l.newEntry()
+	return l.newEntry(DebugLevel)
 
 }
 
 func (l *Logger) INFO() *Entry {
-	return l.newEntry()
+	return l.newEntry(InfoLevel)
 
 }
 
 func (l *Logger) WARN() *Entry {
-	return l.newEntry()
+	return l.newEntry(WarnLevel)
 }
 
 func (l *Logger) ERROR() *Entry {
-	return l.newEntry()
+	return l.newEntry(ErrorLevel)
 }
 
 func (l *Logger) FATAL() *Entry {
-	return l.newEntry()
+	return l.newEntry(FatalLevel)
 }
 
 func (l *Logger) Panic() *Entry {
-	return l.newEntry()
+	return l.newEntry(PanicLevel)
 }
 
 func DEBUG() *Entry {
@@ -1,11 +1,13 @@
 package ox
 
 import (
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"path"
 	"strings"
+	"sync"
 )
 
 type HandlerFunc func(*Context)
@@ -16,54 +18,117 @@ type Application struct {
 	groups    []*RouterGroup
 	templates *template.Template
 	funcMap   template.FuncMap
+
+	// reply 405 with an Allow header when the path only matches other methods
+	HandleMethodNotAllowed bool
+	// answer OPTIONS requests automatically from the registered methods
+	HandleOPTIONS bool
+	// renders the last *Error attached by Context.Error when no response was written
+	ErrorHandler func(*Context, error)
+	// memory used to parse multipart forms, larger files are spooled to temp files
+	MaxMultipartMemory int64
+	// bodies declaring a larger Content-Length are replied with 413 before routing, others fail with 413 once read past it.
+	// 0 for no limit. BodyLimit lowers it per group or route
+	MaxBodySize int64
+
+	noRoute  []HandlerFunc
+	noMethod []HandlerFunc
+	routes   []*Route
+	names    map[string]*Route
+	pool     sync.Pool
 }
 
 func New() *Application {
-	app := &Application{router: newRouter()}
+	app := &Application{
+		router:                 newRouter(),
+		HandleMethodNotAllowed: true,
+		HandleOPTIONS:          true,
+		ErrorHandler:           DefaultErrorHandler,
+		MaxMultipartMemory:     defaultMultipartMemory,
+		noRoute:                []HandlerFunc{notFound},
+		noMethod:               []HandlerFunc{methodNotAllowed},
+		names:                  make(map[string]*Route),
+	}
 	app.RouterGroup = &RouterGroup{app: app}
 	app.groups = []*RouterGroup{app.RouterGroup}
+	app.pool.New = func() interface{} {
+		return app.allocateContext()
+	}
 	return app
 }
 
-func (app *Application) addRoute(method string, pattern string, handler HandlerFunc) {
-	app.router.addRoute(method, pattern, handler)
+func (app *Application) allocateContext() *Context {
+	return &Context{app: app, Params: make(Params, 0, app.router.maxParams)}
 }
 
-// GET method
-func (app *Application) GET(pattern string, handler HandlerFunc) {
-	app.addRoute(http.MethodGet, pattern, handler)
+// every method registered by Any
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions, http.MethodConnect,
 }
 
-// POST method
-func (app *Application) POST(pattern string, handler HandlerFunc) {
-	app.addRoute(http.MethodPost, pattern, handler)
+func (app *Application) addRoute(method string, pattern string, handlers ...HandlerFunc) (*Route, error) {
+	if err := app.router.addRoute(method, pattern, handlers...); err != nil {
+		return nil, err
+	}
+	route := &Route{Method: method, Pattern: pattern, handlers: handlers, app: app}
+	app.routes = append(app.routes, route)
+	return route, nil
 }
 
-// run on port
+// handlers for requests that match no route
+func (app *Application) NoRoute(handlers ...HandlerFunc) {
+	app.noRoute = handlers
+}
+
+// handlers for requests whose path only matches routes of other methods
+func (app *Application) NoMethod(handlers ...HandlerFunc) {
+	app.noMethod = handlers
+}
+
+// run on port, prints the route table first
 func (app *Application) Run(addr string) error {
+	app.PrintRoutes(log.Writer())
 	return http.ListenAndServe(addr, app)
 }
 
-// implement http
-func (app *Application) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middlewares []HandlerFunc
+// the deepest group whose prefix covers path, its middlewares run for unmatched requests
+func (app *Application) groupFor(path string) *RouterGroup {
+	found := app.RouterGroup
 	for _, group := range app.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
+		if len(group.prefix) > len(found.prefix) && group.covers(path) {
+			found = group
 		}
 	}
-	c := newContext(w, req)
-	c.handlers = middlewares
-	c.app = app
+	return found
+}
+
+// implement http
+func (app *Application) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	c := app.pool.Get().(*Context)
+	c.reset(w, req)
+	if cap(c.Params) < app.router.maxParams {
+		c.Params = make(Params, 0, app.router.maxParams)
+	}
 	app.router.handle(c)
+	renderErrors(c)
+	c.writermem.WriteHeaderNow()
+	cleanupMultipart(c)
+	app.pool.Put(c)
 }
 
 // template
 func (app *Application) SetFuncMap(funcMap template.FuncMap) {
 	app.funcMap = funcMap
 }
+
+// templates get a "url" func building paths of named routes, see Application.URL
 func (app *Application) LoadHTMLGlob(pattern string) {
-	app.templates = template.Must(template.New("").Funcs(app.funcMap).ParseGlob(pattern))
+	funcMap := template.FuncMap{"url": app.URL}
+	for name, fn := range app.funcMap {
+		funcMap[name] = fn
+	}
+	app.templates = template.Must(template.New("").Funcs(funcMap).ParseGlob(pattern))
 }
 
 // router group for core
@@ -74,10 +139,11 @@ type RouterGroup struct {
 	app         *Application
 }
 
+// nested group, prefix and middlewares are added to the ones of group
 func (group *RouterGroup) Group(prefix string) *RouterGroup {
 	app := group.app
 	newGroup := &RouterGroup{
-		prefix: prefix,
+		prefix: group.prefix + prefix,
 		parent: group,
 		app:    app,
 	}
@@ -85,22 +151,105 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 	return newGroup
 }
 
-func (group *RouterGroup) addRoute(method string, comp string, handler HandlerFunc) {
+// whether path is under the group prefix, /api covers /api/users but not /apiary
+func (group *RouterGroup) covers(path string) bool {
+	prefix := group.prefix
+	if !strings.HasPrefix(path, prefix) {
+		return false
+	}
+	return len(path) == len(prefix) || prefix == "" || prefix[len(prefix)-1] == '/' || path[len(prefix)] == '/'
+}
+
+// middlewares of the ancestors and the group followed by handlers
+func (group *RouterGroup) combineHandlers(handlers []HandlerFunc) []HandlerFunc {
+	var groups []*RouterGroup
+	size := len(handlers)
+	for g := group; g != nil; g = g.parent {
+		groups = append(groups, g)
+		size += len(g.middlewares)
+	}
+	merged := make([]HandlerFunc, 0, size)
+	for i := len(groups) - 1; i >= 0; i-- {
+		merged = append(merged, groups[i].middlewares...)
+	}
+	return append(merged, handlers...)
+}
+
+// the handler chain is resolved here, middlewares added by Use later do not apply to the route
+func (group *RouterGroup) addRoute(method string, comp string, handlers ...HandlerFunc) (*Route, error) {
 	pattern := group.prefix + comp
-	log.Printf("Route %4s - %s", method, pattern)
-	group.app.router.addRoute(method, pattern, handler)
+	if len(handlers) == 0 {
+		return nil, fmt.Errorf("%s %s: %w: no handlers", method, pattern, ErrInvalidRoute)
+	}
+	return group.app.addRoute(method, pattern, group.combineHandlers(handlers)...)
 }
 
 func (group *RouterGroup) Use(middlewares ...HandlerFunc) {
 	group.middlewares = append(group.middlewares, middlewares...)
 }
 
-func (group *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	group.addRoute(http.MethodGet, pattern, handler)
+// GET method
+func (group *RouterGroup) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return group.MustHandle(http.MethodGet, pattern, handlers...)
+}
+
+// POST method
+func (group *RouterGroup) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return group.MustHandle(http.MethodPost, pattern, handlers...)
+}
+
+// PUT method
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.MustHandle(http.MethodPut, pattern, handlers...)
+}
+
+// PATCH method
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return group.MustHandle(http.MethodPatch, pattern, handlers...)
+}
+
+// DELETE method
+func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return group.MustHandle(http.MethodDelete, pattern, handlers...)
 }
 
-func (group *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	group.addRoute(http.MethodPost, pattern, handler)
+// HEAD method
+func (group *RouterGroup) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return group.MustHandle(http.MethodHead, pattern, handlers...)
+}
+
+// OPTIONS method
+func (group *RouterGroup) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return group.MustHandle(http.MethodOptions, pattern, handlers...)
+}
+
+// CONNECT method
+func (group *RouterGroup) CONNECT(pattern string, handlers ...HandlerFunc) *Route {
+	return group.MustHandle(http.MethodConnect, pattern, handlers...)
+}
+
+// register handlers for the given method, fails on invalid or conflicting patterns
+func (group *RouterGroup) Handle(method string, pattern string, handlers ...HandlerFunc) (*Route, error) {
+	return group.addRoute(method, pattern, handlers...)
+}
+
+// like Handle but panics on error, for startup code
+func (group *RouterGroup) MustHandle(method string, pattern string, handlers ...HandlerFunc) *Route {
+	route, err := group.Handle(method, pattern, handlers...)
+	if err != nil {
+		panic(err)
+	}
+	return route
+}
+
+// register handlers for every method, the routes are in the order of anyMethods.
+// name one of them for Application.URL, the path does not depend on the method
+func (group *RouterGroup) Any(pattern string, handlers ...HandlerFunc) []*Route {
+	routes := make([]*Route, len(anyMethods))
+	for i, method := range anyMethods {
+		routes[i] = group.MustHandle(method, pattern, handlers...)
+	}
+	return routes
 }
 
 func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
@@ -0,0 +1,219 @@
+package ox
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serve(app *Application, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	handler := func(c *Context) { c.String(http.StatusOK, c.Method) }
+	newApp := func() *Application {
+		app := New()
+		app.PUT("/users/:id", handler)
+		app.GET("/users/:id", handler)
+		app.DELETE("/users/:id", handler)
+		app.GET("/opts", handler)
+		app.OPTIONS("/opts", handler)
+		return app
+	}
+	tests := []struct {
+		name         string
+		notAllowed   bool
+		options      bool
+		method, path string
+		code         int
+		allow, body  string
+	}{
+		{"405 sorted", true, true, "POST", "/users/1", 405, "DELETE, GET, OPTIONS, PUT", ""},
+		{"405 without auto options", true, false, "POST", "/users/1", 405, "DELETE, GET, PUT", ""},
+		{"405 with options route", true, true, "POST", "/opts", 405, "GET, OPTIONS", ""},
+		{"404 when disabled", false, true, "POST", "/users/1", 404, "", ""},
+		{"404 unknown path", true, true, "POST", "/nope", 404, "", ""},
+		{"auto options", true, true, "OPTIONS", "/users/1", 204, "DELETE, GET, OPTIONS, PUT", ""},
+		{"auto options without 405", false, true, "OPTIONS", "/users/1", 204, "DELETE, GET, OPTIONS, PUT", ""},
+		{"options disabled", true, false, "OPTIONS", "/users/1", 405, "DELETE, GET, PUT", ""},
+		{"options both disabled", false, false, "OPTIONS", "/users/1", 404, "", ""},
+		{"options route wins", true, true, "OPTIONS", "/opts", 200, "", "OPTIONS"},
+		{"options unknown path", true, true, "OPTIONS", "/nope", 404, "", ""},
+		{"matched", true, true, "GET", "/users/1", 200, "", "GET"},
+	}
+	for _, tt := range tests {
+		app := newApp()
+		app.HandleMethodNotAllowed = tt.notAllowed
+		app.HandleOPTIONS = tt.options
+		w := serve(app, tt.method, tt.path)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.name, w.Code, tt.code)
+		}
+		if got := w.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s: Allow %q, want %q", tt.name, got, tt.allow)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: body %q, want %q", tt.name, w.Body.String(), tt.body)
+		}
+	}
+}
+
+func TestNoRouteNoMethod(t *testing.T) {
+	app := New()
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	app.Use(mark("root"))
+	api := app.Group("/api")
+	api.Use(mark("api"))
+	api.GET("/users", func(c *Context) {})
+	app.NoRoute(mark("noRoute"), func(c *Context) { c.String(http.StatusNotFound, "no route") })
+	app.NoMethod(mark("noMethod"), func(c *Context) { c.String(http.StatusMethodNotAllowed, "no method") })
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+		trace        string
+	}{
+		{"GET", "/api/nope", 404, "no route", "root api noRoute"},
+		{"GET", "/nope", 404, "no route", "root noRoute"},
+		{"GET", "/apiary", 404, "no route", "root noRoute"},
+		{"POST", "/api/users", 405, "no method", "root api noMethod"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		w := serve(app, tt.method, tt.path)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("%s %s: got %d %q, want %d %q", tt.method, tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+		if got := strings.Join(trace, " "); got != tt.trace {
+			t.Errorf("%s %s: ran %q, want %q", tt.method, tt.path, got, tt.trace)
+		}
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	app := New()
+	var got []error
+	app.ErrorHandler = func(c *Context, err error) {
+		got = append(got, err)
+		c.JSON(http.StatusTeapot, map[string]string{"error": err.Error()})
+	}
+	app.GET("/fail", func(c *Context) {
+		c.Error(errors.New("first"))
+		c.Error(NewHTTPError(http.StatusConflict, "second"))
+	})
+	app.GET("/written", func(c *Context) {
+		c.Error(errors.New("ignored"))
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := serve(app, "GET", "/fail")
+	if w.Code != http.StatusTeapot || !strings.Contains(w.Body.String(), `"second"`) {
+		t.Errorf("/fail: got %d %s", w.Code, w.Body.String())
+	}
+	var he *HTTPError
+	if len(got) != 1 || !errors.As(got[0], &he) || he.Code != http.StatusConflict {
+		t.Errorf("/fail: handler got %v, want the last error", got)
+	}
+
+	got = nil
+	w = serve(app, "GET", "/written")
+	if w.Code != http.StatusOK || w.Body.String() != "ok" || len(got) != 0 {
+		t.Errorf("/written: got %d %q, handler called with %v", w.Code, w.Body.String(), got)
+	}
+
+	// the default 404 goes through the error handler too
+	got = nil
+	w = serve(app, "GET", "/nope")
+	if w.Code != http.StatusTeapot || len(got) != 1 || !errors.As(got[0], &he) || he.Code != http.StatusNotFound {
+		t.Errorf("/nope: got %d, handler called with %v", w.Code, got)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	app := New()
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	handler := func(c *Context) { trace = append(trace, "handler "+c.FullPath()) }
+	app.Use(mark("root"))
+	api := app.Group("/api")
+	api.Use(mark("api"))
+	v1 := api.Group("/v1")
+	v1.Use(mark("v1"))
+	v1.GET("/users", handler)
+	api.GET("/status", handler)
+	app.GET("/apiary", handler)
+	// middlewares added later do not change registered routes
+	api.Use(mark("late"))
+	tests := []struct {
+		path  string
+		code  int
+		trace string
+	}{
+		{"/api/v1/users", 200, "root api v1 handler /api/v1/users"},
+		{"/api/status", 200, "root api handler /api/status"},
+		{"/apiary", 200, "root handler /apiary"},
+		{"/v1/users", 404, "root"},
+		{"/api/v1/nope", 404, "root api late v1"},
+		{"/apiary/x", 404, "root"},
+	}
+	for _, tt := range tests {
+		trace = nil
+		w := serve(app, "GET", tt.path)
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.path, w.Code, tt.code)
+		}
+		if got := strings.Join(trace, " "); got != tt.trace {
+			t.Errorf("%s: ran %q, want %q", tt.path, got, tt.trace)
+		}
+	}
+}
+
+// ServeHTTP allocating a new Context per request, the behaviour before contexts were pooled
+func serveUnpooled(app *Application, w http.ResponseWriter, req *http.Request) {
+	c := app.allocateContext()
+	c.reset(w, req)
+	app.router.handle(c)
+	renderErrors(c)
+	c.writermem.WriteHeaderNow()
+	cleanupMultipart(c)
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	app := New()
+	handler := func(c *Context) {}
+	for _, p := range []string{"/", "/users", "/users/:id", "/users/:id/posts/:post", "/files/*path", "/api/v1/items"} {
+		app.GET(p, handler)
+	}
+	for _, path := range []string{"/api/v1/items", "/users/42/posts/7", "/files/a/b/c.txt"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := &discardWriter{}
+		b.Run(path+"/pooled", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				app.ServeHTTP(w, req)
+			}
+		})
+		b.Run(path+"/unpooled", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				serveUnpooled(app, w, req)
+			}
+		})
+	}
+}
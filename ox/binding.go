@@ -0,0 +1,195 @@
+package ox
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// default Application.MaxMultipartMemory, larger files are stored on disk
+const defaultMultipartMemory = 32 << 20
+
+// decodes a request into a struct or map
+type Binding interface {
+	Name() string
+	Bind(*http.Request, interface{}) error
+}
+
+var (
+	// request body as json
+	JSONBinding Binding = jsonBinding{}
+	// request body as xml
+	XMLBinding Binding = xmlBinding{}
+	// query and urlencoded body, fields by the `form` tag
+	FormBinding Binding = formBinding{}
+	// query only, fields by the `form` tag
+	QueryBinding Binding = queryBinding{}
+	// request headers, fields by the `header` tag
+	HeaderBinding Binding = headerBinding{}
+	// query and multipart body, fields by the `form` tag
+	MultipartBinding Binding = multipartBinding{}
+)
+
+// error of a binding, replied as 400 unless the cause carries its own status
+type BindError struct {
+	Binding string
+	Err     error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("bind %s: %s", e.Binding, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BindError) StatusCode() int {
+	var sc statusCoder
+	if errors.As(e.Err, &sc) {
+		return sc.StatusCode()
+	}
+	return http.StatusBadRequest
+}
+
+type jsonBinding struct{}
+
+func (jsonBinding) Name() string { return "json" }
+
+func (jsonBinding) Bind(req *http.Request, obj interface{}) error {
+	if req.Body == nil {
+		return errors.New("empty body")
+	}
+	return json.NewDecoder(req.Body).Decode(obj)
+}
+
+type xmlBinding struct{}
+
+func (xmlBinding) Name() string { return "xml" }
+
+func (xmlBinding) Bind(req *http.Request, obj interface{}) error {
+	if req.Body == nil {
+		return errors.New("empty body")
+	}
+	return xml.NewDecoder(req.Body).Decode(obj)
+}
+
+type formBinding struct{}
+
+func (formBinding) Name() string { return "form" }
+
+func (formBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseForm(); err != nil {
+		return err
+	}
+	return mapValues(obj, formSource(req.Form), "form")
+}
+
+type queryBinding struct{}
+
+func (queryBinding) Name() string { return "query" }
+
+func (queryBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapValues(obj, formSource(req.URL.Query()), "form")
+}
+
+type headerBinding struct{}
+
+func (headerBinding) Name() string { return "header" }
+
+func (headerBinding) Bind(req *http.Request, obj interface{}) error {
+	return mapValues(obj, headerSource(req.Header), "header")
+}
+
+type multipartBinding struct{}
+
+func (multipartBinding) Name() string { return "multipart" }
+
+func (multipartBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		return err
+	}
+	return mapValues(obj, formSource(req.Form), "form")
+}
+
+// binding for the request method and content type, bodies of unknown type are read as json
+func bindingFor(method string, contentType string) Binding {
+	if method == http.MethodGet || method == http.MethodHead {
+		return FormBinding
+	}
+	switch {
+	case contentType == "application/xml" || contentType == "text/xml":
+		return XMLBinding
+	case contentType == "application/x-www-form-urlencoded":
+		return FormBinding
+	case contentType == "multipart/form-data":
+		return MultipartBinding
+	}
+	return JSONBinding
+}
+
+// media type of the request without parameters, "" if missing
+func (c *Context) ContentType() string {
+	ct := c.Req.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		return mt
+	}
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = ct[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(ct))
+}
+
+// bind by method and content type: json, xml, form or multipart
+func (c *Context) Bind(obj interface{}) error {
+	return c.BindWith(obj, bindingFor(c.Method, c.ContentType()))
+}
+
+// bind with b and Validate the result, decoding failures are *BindError
+func (c *Context) BindWith(obj interface{}, b Binding) error {
+	if b == MultipartBinding {
+		if _, err := c.MultipartForm(); err != nil {
+			return &BindError{Binding: b.Name(), Err: err}
+		}
+	}
+	if err := b.Bind(c.Req, obj); err != nil {
+		return &BindError{Binding: b.Name(), Err: c.bodyError(err)}
+	}
+	return Validate(obj)
+}
+
+func (c *Context) BindJSON(obj interface{}) error {
+	return c.BindWith(obj, JSONBinding)
+}
+
+func (c *Context) BindXML(obj interface{}) error {
+	return c.BindWith(obj, XMLBinding)
+}
+
+func (c *Context) BindForm(obj interface{}) error {
+	return c.BindWith(obj, FormBinding)
+}
+
+func (c *Context) BindQuery(obj interface{}) error {
+	return c.BindWith(obj, QueryBinding)
+}
+
+func (c *Context) BindHeader(obj interface{}) error {
+	return c.BindWith(obj, HeaderBinding)
+}
+
+// bind path params, fields by the `uri` tag
+func (c *Context) BindURI(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		values[p.Key] = append(values[p.Key], p.Value)
+	}
+	if err := mapValues(obj, formSource(values), "uri"); err != nil {
+		return &BindError{Binding: "uri", Err: err}
+	}
+	return Validate(obj)
+}
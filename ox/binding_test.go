@@ -0,0 +1,117 @@
+package ox
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bindingTarget struct {
+	Name string `json:"name" xml:"name" form:"name"`
+}
+
+func TestBind(t *testing.T) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	_ = mw.WriteField("name", "multipart")
+	_ = mw.Close()
+
+	tests := []struct {
+		method      string
+		target      string
+		contentType string
+		body        string
+		want        string
+	}{
+		{http.MethodPost, "/", "application/json", `{"name":"json"}`, "json"},
+		{http.MethodPost, "/", "application/json; charset=utf-8", `{"name":"json"}`, "json"},
+		{http.MethodPost, "/", "application/xml", `<b><name>xml</name></b>`, "xml"},
+		{http.MethodPost, "/", "text/xml", `<b><name>xml</name></b>`, "xml"},
+		{http.MethodPost, "/", "application/x-www-form-urlencoded", "name=form", "form"},
+		{http.MethodPost, "/", mw.FormDataContentType(), buf.String(), "multipart"},
+		{http.MethodPost, "/", "", `{"name":"default"}`, "default"},
+		{http.MethodGet, "/?name=query", "", "", "query"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		c := &Context{Req: req, Method: req.Method, app: New()}
+		var got bindingTarget
+		if err := c.Bind(&got); err != nil {
+			t.Errorf("%s %q: %v", tt.method, tt.contentType, err)
+			continue
+		}
+		if got.Name != tt.want {
+			t.Errorf("%s %q: got %q, want %q", tt.method, tt.contentType, got.Name, tt.want)
+		}
+	}
+}
+
+func TestBindError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{"))
+	req.Header.Set("Content-Type", "application/json")
+	c := &Context{Req: req, Method: req.Method, app: New()}
+	var got bindingTarget
+	err := c.Bind(&got)
+	var be *BindError
+	if !errors.As(err, &be) || be.Binding != "json" || be.StatusCode() != http.StatusBadRequest {
+		t.Fatalf("got %v", err)
+	}
+	if e := newError(err); e.Status != http.StatusBadRequest || !e.IsType(ErrorTypePublic) {
+		t.Errorf("got status %d, type %d", e.Status, e.Type)
+	}
+}
+
+func TestBindHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-Id", "id")
+	req.Header.Add("Accept", "a")
+	req.Header.Add("Accept", "b")
+	req.Header.Set("X-Count", "3")
+	c := &Context{Req: req}
+	var got struct {
+		ID     string   `header:"x-request-id"`
+		Accept []string `header:"accept"`
+		Count  int      `header:"X-COUNT"`
+	}
+	if err := c.BindHeader(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "id" || len(got.Accept) != 2 || got.Count != 3 {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestBindURI(t *testing.T) {
+	app := New()
+	var got struct {
+		ID   int    `uri:"id"`
+		Name string `uri:"name"`
+	}
+	var err error
+	app.GET("/users/:id/:name", func(c *Context) { err = c.BindURI(&got) })
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/7/bob", nil))
+	if err != nil || got.ID != 7 || got.Name != "bob" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/x/bob", nil))
+	var be *BindError
+	if !errors.As(err, &be) || be.Binding != "uri" {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func TestPostForm(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?k=query", strings.NewReader("k=body"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := &Context{Req: req}
+	if got := c.PostForm("k"); got != "body" {
+		t.Errorf("got %q, want body", got)
+	}
+}
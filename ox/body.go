@@ -0,0 +1,155 @@
+package ox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+)
+
+// error reading a request body beyond its limit, replied as 413
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body larger than %d bytes", e.Limit)
+}
+
+func (e *BodyTooLargeError) StatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// body failing with *BodyTooLargeError after limit bytes, kept in the Context to avoid an allocation
+type limitedBody struct {
+	rc    io.ReadCloser
+	n     int64 // bytes left
+	limit int64
+	err   error
+}
+
+func (l *limitedBody) reset(rc io.ReadCloser, limit int64) {
+	*l = limitedBody{rc: rc, n: limit, limit: limit}
+}
+
+// change the limit, bytes already read count against it
+func (l *limitedBody) setLimit(limit int64) {
+	read := l.limit - l.n
+	l.limit = limit
+	l.n = limit - read
+	if l.n < 0 {
+		l.n = 0
+	}
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	// read one byte more than allowed to tell a body of exactly limit bytes from a larger one
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.rc.Read(p)
+	if int64(n) <= l.n {
+		l.n -= int64(n)
+		l.err = err
+		return n, err
+	}
+	n = int(l.n)
+	l.n = 0
+	l.err = &BodyTooLargeError{Limit: l.limit}
+	return n, l.err
+}
+
+func (l *limitedBody) Close() error {
+	return l.rc.Close()
+}
+
+// limit the request body to n bytes, a limit set before is only lowered
+func (c *Context) limitBody(n int64) {
+	if c.Req.Body == nil || c.Req.Body == http.NoBody {
+		return
+	}
+	if c.Req.Body == &c.bodymem {
+		if n < c.bodymem.limit {
+			c.bodymem.setLimit(n)
+		}
+		return
+	}
+	c.bodymem.reset(c.Req.Body, n)
+	c.Req.Body = &c.bodymem
+}
+
+// the *BodyTooLargeError of a limited body, parsers such as mime/multipart drop the original error
+func (c *Context) bodyError(err error) error {
+	if err != nil && c.Req.Body == &c.bodymem && c.bodymem.err != nil {
+		var tooLarge *BodyTooLargeError
+		if errors.As(c.bodymem.err, &tooLarge) {
+			return tooLarge
+		}
+	}
+	return err
+}
+
+// middleware limiting the request body of a group or route to n bytes, such as the maximum upload size.
+// it can only lower Application.MaxBodySize, which stays the limit of the whole application.
+// bodies declaring a larger Content-Length are replied with 413 right away,
+// others fail with *BodyTooLargeError once the handler reads past the limit
+func BodyLimit(n int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Req.ContentLength > n {
+			c.AbortWithError(http.StatusRequestEntityTooLarge, &BodyTooLargeError{Limit: n})
+			return
+		}
+		c.limitBody(n)
+		c.Next()
+	}
+}
+
+// replies 413 to requests declaring a body larger than Application.MaxBodySize
+func bodyTooLarge(c *Context) {
+	c.AbortWithError(http.StatusRequestEntityTooLarge, &BodyTooLargeError{Limit: c.app.MaxBodySize})
+}
+
+// get from body
+func (c *Context) Body() ([]byte, error) {
+	body, err := ioutil.ReadAll(c.Req.Body)
+	return body, c.bodyError(err)
+}
+
+// decoder reading the body value by value, for NDJSON and other streams of json values
+func (c *Context) BodyStream() *json.Decoder {
+	return json.NewDecoder(c.Req.Body)
+}
+
+// decode the body as a stream of json values into v, calling fn after each one.
+// v is zeroed before every value and validated like Bind, it stops at the first error
+func (c *Context) DecodeStream(v interface{}, fn func() error) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("decode stream: expects a non-nil pointer")
+	}
+	dec := c.BodyStream()
+	for i := 0; ; i++ {
+		rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+		if err := dec.Decode(v); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return &BindError{Binding: "json", Err: fmt.Errorf("value %d: %w", i, c.bodyError(err))}
+		}
+		if err := Validate(v); err != nil {
+			return fmt.Errorf("value %d: %w", i, err)
+		}
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+}
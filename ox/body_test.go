@@ -0,0 +1,68 @@
+package ox
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLimit(t *testing.T) {
+	app := New()
+	app.MaxBodySize = 10
+	var ran []string
+	app.Use(func(c *Context) {
+		ran = append(ran, "mw")
+		c.Next()
+	})
+	read := func(c *Context) {
+		ran = append(ran, "handler")
+		b, err := c.Body()
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(b))
+	}
+	app.POST("/a", read)
+	app.POST("/small", BodyLimit(3), read)
+	app.POST("/big", BodyLimit(100), read)
+	tests := []struct {
+		path    string
+		size    int
+		chunked bool
+		code    int
+		ran     string
+	}{
+		{"/a", 10, false, 200, "mw handler"},
+		{"/a", 10, true, 200, "mw handler"},
+		// a declared length is rejected before routing, middlewares still run
+		{"/a", 11, false, 413, "mw"},
+		{"/nope", 11, false, 413, "mw"},
+		{"/a", 11, true, 413, "mw handler"},
+		{"/small", 3, false, 200, "mw handler"},
+		{"/small", 4, false, 413, "mw"},
+		{"/small", 4, true, 413, "mw handler"},
+		// BodyLimit cannot raise the application limit
+		{"/big", 10, true, 200, "mw handler"},
+		{"/big", 50, false, 413, "mw"},
+		{"/big", 50, true, 413, "mw handler"},
+	}
+	for _, tt := range tests {
+		ran = nil
+		req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(strings.Repeat("x", tt.size)))
+		if tt.chunked {
+			req.Body = ioutil.NopCloser(req.Body)
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s %d chunked=%v: got %d, want %d", tt.path, tt.size, tt.chunked, w.Code, tt.code)
+		}
+		if got := strings.Join(ran, " "); got != tt.ran {
+			t.Errorf("%s %d chunked=%v: ran %q, want %q", tt.path, tt.size, tt.chunked, got, tt.ran)
+		}
+	}
+}
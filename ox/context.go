@@ -1,47 +1,197 @@
 package ox
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 type M map[string]interface{}
 
+// index of an aborted chain, beyond any handler
+const abortIndex = math.MaxInt32 / 2
+
 type Context struct {
-	Writer     http.ResponseWriter
+	Writer     ResponseWriter
 	Req        *http.Request
 	Path       string
 	Method     string
 	StatusCode int
-	Params     map[string]string
+	Params     Params
 	handlers   []HandlerFunc
 	index      int
 	app        *Application
+	errors     Errors
+	mu         sync.RWMutex
+	keys       map[string]interface{}
+	writermem  responseWriter
+	bodymem    limitedBody
+	fullPath   string
+}
+
+// contexts are pooled by the Application, every field of a request is cleared here
+func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
+	c.writermem.reset(w)
+	c.Writer = &c.writermem
+	c.Req = req
+	c.Path = req.URL.Path
+	c.Method = req.Method
+	c.StatusCode = 0
+	c.Params = c.Params[:0]
+	c.handlers = nil
+	c.index = -1
+	c.errors = c.errors[:0]
+	c.keys = nil
+	c.fullPath = ""
+	c.bodymem = limitedBody{}
+	if limit := c.app.MaxBodySize; limit > 0 {
+		c.limitBody(limit)
+	}
+}
+
+// copy of the context that stays valid after the request, for handing to goroutines.
+// its request context keeps the values but is never cancelled, and the copy cannot run the chain.
+// responses written through the copy are discarded
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		writermem:  responseWriter{ResponseWriter: &discardWriter{}, status: c.writermem.status, size: c.writermem.size},
+		Req:        c.Req.WithContext(detachedContext{c.Req.Context()}),
+		Path:       c.Path,
+		Method:     c.Method,
+		StatusCode: c.StatusCode,
+		fullPath:   c.fullPath,
+		Params:     make(Params, len(c.Params)),
+		index:      abortIndex,
+		app:        c.app,
+		errors:     append(Errors(nil), c.errors...),
+	}
+	cp.Writer = &cp.writermem
+	copy(cp.Params, c.Params)
+	c.mu.RLock()
+	if c.keys != nil {
+		cp.keys = make(map[string]interface{}, len(c.keys))
+		for k, v := range c.keys {
+			cp.keys[k] = v
+		}
+		cp.Req = cp.Req.WithContext(&keysContext{Context: cp.Req.Context(), mu: &cp.mu, keys: cp.keys})
+	}
+	c.mu.RUnlock()
+	return cp
+}
+
+// values of the parent context without its deadline and cancellation, net/http cancels
+// the request context once ServeHTTP returns
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) { return }
+func (detachedContext) Done() <-chan struct{}                   { return nil }
+func (detachedContext) Err() error                              { return nil }
+
+func (dc detachedContext) Value(key interface{}) interface{} {
+	return dc.parent.Value(key)
 }
 
-func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Writer: w,
-		Req:    req,
-		Path:   req.URL.Path,
-		Method: req.Method,
-		index:  -1,
+// context running the rest of the chain on another goroutine and writing to w, see Timeout.
+// it is not pooled and owns its request and limited body, so it stays usable by its goroutine
+// when c goes back to the pool
+func (c *Context) fork(w http.ResponseWriter) *Context {
+	f := &Context{
+		Req:        c.Req.WithContext(c.Req.Context()),
+		Path:       c.Path,
+		Method:     c.Method,
+		StatusCode: c.StatusCode,
+		fullPath:   c.fullPath,
+		Params:     make(Params, len(c.Params)),
+		handlers:   c.handlers,
+		index:      c.index,
+		app:        c.app,
+	}
+	f.writermem.reset(w)
+	f.Writer = &f.writermem
+	if c.Req.Body == &c.bodymem {
+		f.bodymem = c.bodymem
+		f.Req.Body = &f.bodymem
 	}
+	copy(f.Params, c.Params)
+	c.mu.RLock()
+	if c.keys != nil {
+		f.keys = make(map[string]interface{}, len(c.keys))
+		for k, v := range c.keys {
+			f.keys[k] = v
+		}
+		f.Req = f.Req.WithContext(&keysContext{Context: f.Req.Context(), mu: &f.mu, keys: f.keys})
+	}
+	c.mu.RUnlock()
+	return f
+}
+
+// Context implements context.Context on top of the request context,
+// so it is cancelled when the client goes away or a Timeout fires
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	return c.Req.Context().Err()
+}
+
+// values of Set first, then the request context
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if value, exists := c.Get(k); exists {
+			return value
+		}
+	}
+	return c.Req.Context().Value(key)
 }
 
 // middleware function Next
 func (c *Context) Next() {
 	c.index++
-	for s := len(c.handlers); c.index < s; c.index++ {
+	for c.index < len(c.handlers) {
 		c.handlers[c.index](c)
+		c.index++
 	}
 }
 
-// get value from post form
+// stop the chain, handlers after the current one are not called
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+// whether the chain was aborted
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+// abort and write the status code
+func (c *Context) AbortWithStatus(code int) {
+	c.Abort()
+	c.Status(code)
+}
+
+// abort and write obj as json
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.JSON(code, obj)
+}
+
+// get value from post form, the body is parsed on first use
 func (c *Context) PostForm(key string) string {
-	return c.Req.PostForm.Get(key)
+	return c.Req.PostFormValue(key)
 }
 
 // get from query
@@ -49,21 +199,107 @@ func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
 }
 
+// pattern of the matched route, "" for unmatched requests
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// client ip from X-Forwarded-For, X-Real-Ip or the remote address
+func (c *Context) ClientIP() string {
+	if forwarded := c.Req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		if i := strings.IndexByte(forwarded, ','); i >= 0 {
+			forwarded = forwarded[:i]
+		}
+		if ip := strings.TrimSpace(forwarded); ip != "" {
+			return ip
+		}
+	}
+	if ip := strings.TrimSpace(c.Req.Header.Get("X-Real-Ip")); ip != "" {
+		return ip
+	}
+	if ip, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr)); err == nil {
+		return ip
+	}
+	return c.Req.RemoteAddr
+}
+
 // get from params
 func (c *Context) Param(key string) string {
-	value, _ := c.Params[key]
-	return value
+	return c.Params.ByName(key)
+}
+
+// get from params, fails if missing
+func (c *Context) mustParam(key string) (string, error) {
+	value, ok := c.Params.Get(key)
+	if !ok {
+		return "", fmt.Errorf("param %s: not found", key)
+	}
+	return value, nil
 }
 
-// get from body
-func (c *Context) Body() ([]byte, error) {
-	return ioutil.ReadAll(c.Req.Body)
+// get int from params
+func (c *Context) ParamInt(key string) (int, error) {
+	value, err := c.mustParam(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", key, err)
+	}
+	return i, nil
+}
+
+// get int64 from params
+func (c *Context) ParamInt64(key string) (int64, error) {
+	value, err := c.mustParam(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", key, err)
+	}
+	return i, nil
+}
+
+// get uint64 from params
+func (c *Context) ParamUint64(key string) (uint64, error) {
+	value, err := c.mustParam(key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", key, err)
+	}
+	return i, nil
+}
+
+// get float64 from params
+func (c *Context) ParamFloat64(key string) (float64, error) {
+	value, err := c.mustParam(key)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("param %s: %w", key, err)
+	}
+	return f, nil
 }
 
-// get from body binding struct or map
-func (c *Context) Bind(bind interface{}) error {
-	decoder := json.NewDecoder(c.Req.Body)
-	return decoder.Decode(bind)
+// get bool from params
+func (c *Context) ParamBool(key string) (bool, error) {
+	value, err := c.mustParam(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("param %s: %w", key, err)
+	}
+	return b, nil
 }
 
 // set header
@@ -71,47 +307,102 @@ func (c *Context) SetHeader(key string, value string) {
 	c.Writer.Header().Set(key, value)
 }
 
-// set status code
+// set status code, it is sent with the first write of the body
 func (c *Context) Status(code int) {
 	c.StatusCode = code
 	c.Writer.WriteHeader(code)
 }
 
+// attach an error to the context, the chain keeps running.
+// the last error is rendered by the Application.ErrorHandler if no response was written
+func (c *Context) Error(err error) *Error {
+	e := newError(err)
+	c.errors = append(c.errors, e)
+	return e
+}
+
+// errors attached by the handlers so far
+func (c *Context) Errors() Errors {
+	return c.errors
+}
+
+// abort and attach err to be rendered with the status code
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.Abort()
+	return c.Error(err).SetStatus(code)
+}
+
 // fail to json
 func (c *Context) Fail(code int, err string) {
-	c.index = len(c.handlers)
-	c.JSON(code, M{"message": err})
+	c.AbortWithStatusJSON(code, M{"message": err})
 }
 
 // string
 func (c *Context) String(code int, format string, values ...interface{}) {
-	c.Status(code)
-	c.SetHeader("Content-Type", "text/plain")
-	_, _ = fmt.Fprintf(c.Writer, format, values)
+	c.Render(code, StringRender{Format: format, Values: values})
 }
 
 // byte
 func (c *Context) Data(code int, data []byte) {
-	c.Status(code)
-	_, _ = c.Writer.Write(data)
+	c.Render(code, DataRender{Data: data})
+}
 
+// write data from reader, contentLength is sent when not negative
+func (c *Context) DataFromReader(code int, contentLength int64, contentType string, reader io.Reader, extraHeaders map[string]string) {
+	c.Render(code, ReaderRender{Type: contentType, Length: contentLength, Reader: reader, Headers: extraHeaders})
 }
 
 // write json data
 func (c *Context) JSON(code int, obj interface{}) {
-	c.Status(code)
-	c.SetHeader("Content-Type", "application/json")
-	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
-		http.Error(c.Writer, err.Error(), 500)
+	c.Render(code, JSONRender{Data: obj})
+}
+
+// write indented json
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	c.Render(code, IndentedJSONRender{Data: obj})
+}
+
+// write json with non-ascii characters escaped
+func (c *Context) AsciiJSON(code int, obj interface{}) {
+	c.Render(code, AsciiJSONRender{Data: obj})
+}
+
+// write json without escaping html characters
+func (c *Context) PureJSON(code int, obj interface{}) {
+	c.Render(code, PureJSONRender{Data: obj})
+}
+
+// write json wrapped in the function named by the callback query, plain json without it.
+// an invalid callback is replied with 400
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback == "" {
+		c.JSON(code, obj)
+		return
+	}
+	if !ValidJSONPCallback(callback) {
+		c.AbortWithError(http.StatusBadRequest, NewHTTPError(http.StatusBadRequest, "invalid jsonp callback"))
+		return
 	}
+	c.Render(code, JSONPRender{Callback: callback, Data: obj})
+}
+
+// write xml data
+func (c *Context) XML(code int, obj interface{}) {
+	c.Render(code, XMLRender{Data: obj})
+}
+
+// write yaml data
+func (c *Context) YAML(code int, obj interface{}) {
+	c.Render(code, YAMLRender{Data: obj})
+}
+
+// write msgpack data
+func (c *Context) MsgPack(code int, obj interface{}) {
+	c.Render(code, MsgPackRender{Data: obj})
 }
 
-// write html
+// write html, the template is rendered before anything is sent so a failure can still reply 500
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.Status(code)
-	c.SetHeader("Content-Type", "text/html")
-	if err := c.app.templates.ExecuteTemplate(c.Writer, name, data); err != nil {
-		c.Fail(500, err.Error())
-	}
+	c.Render(code, HTMLRender{Template: c.app.templates, Name: name, Data: data})
 }
@@ -0,0 +1,57 @@
+package ox
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	app := New()
+	copied := make(chan *Context, 1)
+	app.GET("/users/:id", func(c *Context) {
+		c.Set("user", "bob")
+		c.Status(http.StatusAccepted)
+		copied <- c.Copy()
+		c.String(http.StatusAccepted, "original")
+	})
+	w := serve(app, "GET", "/users/7")
+	if w.Code != http.StatusAccepted || w.Body.String() != "original" {
+		t.Fatalf("got %d %q", w.Code, w.Body.String())
+	}
+	cp := <-copied
+
+	// the pooled context is reused for the next request
+	serve(app, "GET", "/nope")
+	if cp.Param("id") != "7" || cp.FullPath() != "/users/:id" || cp.Path != "/users/7" {
+		t.Errorf("got param %q, full path %q, path %q", cp.Param("id"), cp.FullPath(), cp.Path)
+	}
+	if v, _ := cp.Get("user"); v != "bob" {
+		t.Errorf("got key %v", v)
+	}
+	if v, _ := cp.Req.Context().Value("user").(string); v != "bob" {
+		t.Errorf("got request context value %q", v)
+	}
+	cp.Set("late", 1)
+	if cp.Req.Context().Value("late") != 1 {
+		t.Error("keys set on the copy are not visible through its request context")
+	}
+
+	// the request is over, the copy is neither cancelled nor bounded by a deadline
+	ctx := cp.Req.Context()
+	if ctx.Err() != nil || ctx.Done() != nil {
+		t.Errorf("got err %v", ctx.Err())
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("got a deadline")
+	}
+
+	// writes are discarded instead of panicking or reaching the original response
+	cp.SetHeader("X-Copy", "1")
+	cp.JSON(http.StatusOK, map[string]int{"a": 1})
+	cp.String(http.StatusOK, "copy")
+	cp.Writer.Flush()
+	if w.Header().Get("X-Copy") != "" || w.Body.String() != "original" {
+		t.Errorf("copy wrote to the response: %v %q", w.Header(), w.Body.String())
+	}
+	cp.Next()
+}
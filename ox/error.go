@@ -0,0 +1,211 @@
+package ox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+)
+
+// error carrying the http status code to reply with
+type HTTPError struct {
+	Code    int
+	Message string
+}
+
+func NewHTTPError(code int, message string) *HTTPError {
+	if message == "" {
+		message = http.StatusText(code)
+	}
+	return &HTTPError{Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+type ErrorType uint8
+
+const (
+	// message is only logged, clients see the status text
+	ErrorTypePrivate ErrorType = 1 << iota
+	// message is shown to clients
+	ErrorTypePublic
+
+	ErrorTypeAny ErrorType = 1<<8 - 1
+)
+
+// error attached to the context by Context.Error
+type Error struct {
+	Err    error
+	Type   ErrorType
+	Status int
+	Meta   interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) SetType(t ErrorType) *Error {
+	e.Type = t
+	return e
+}
+
+func (e *Error) SetStatus(code int) *Error {
+	e.Status = code
+	return e
+}
+
+func (e *Error) SetMeta(meta interface{}) *Error {
+	e.Meta = meta
+	return e
+}
+
+func (e *Error) IsType(t ErrorType) bool {
+	return e.Type&t > 0
+}
+
+// status to reply with, 500 when unset
+func (e *Error) StatusCode() int {
+	if e.Status != 0 {
+		return e.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// errors of a request in the order they were raised
+type Errors []*Error
+
+// last error, nil if empty
+func (errs Errors) Last() *Error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[len(errs)-1]
+}
+
+// errors of the given type
+func (errs Errors) ByType(t ErrorType) Errors {
+	var result Errors
+	for _, e := range errs {
+		if e.IsType(t) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (errs Errors) String() string {
+	var b strings.Builder
+	for i, e := range errs {
+		_, _ = fmt.Fprintf(&b, "Error #%02d: %s\n", i+1, e.Err)
+		if e.Meta != nil {
+			_, _ = fmt.Fprintf(&b, "     Meta: %v\n", e.Meta)
+		}
+	}
+	return b.String()
+}
+
+// errors knowing the status code to reply with, such as *BindError
+type statusCoder interface {
+	StatusCode() int
+}
+
+// wrap err as *Error, a *HTTPError or a statusCoder is public and keeps its status.
+// the field errors of ValidationErrors become the meta
+func newError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	e = &Error{Err: err, Type: ErrorTypePrivate}
+	var he *HTTPError
+	var sc statusCoder
+	if errors.As(err, &he) {
+		e.Type = ErrorTypePublic
+		e.Status = he.Code
+	} else if errors.As(err, &sc) {
+		e.Type = ErrorTypePublic
+		e.Status = sc.StatusCode()
+	}
+	var ve ValidationErrors
+	if errors.As(err, &ve) {
+		e.Meta = ve
+	}
+	return e
+}
+
+// RFC 7807 problem document
+type Problem struct {
+	Type     string      `json:"type"`
+	Title    string      `json:"title"`
+	Status   int         `json:"status"`
+	Detail   string      `json:"detail,omitempty"`
+	Instance string      `json:"instance,omitempty"`
+	Meta     interface{} `json:"meta,omitempty"`
+}
+
+func newProblem(c *Context, err error) *Problem {
+	e := newError(err)
+	status := e.StatusCode()
+	problem := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Instance: c.Path,
+	}
+	if e.IsType(ErrorTypePublic) {
+		problem.Detail = e.Error()
+		problem.Meta = e.Meta
+	}
+	return problem
+}
+
+// default Application.ErrorHandler, writes an html page for browsers and a problem document otherwise
+func DefaultErrorHandler(c *Context, err error) {
+	problem := newProblem(c, err)
+	if strings.Contains(c.Req.Header.Get("Accept"), "text/html") {
+		c.SetHeader("Content-Type", "text/html; charset=utf-8")
+		c.Status(problem.Status)
+		_, _ = fmt.Fprintf(c.Writer, "<!DOCTYPE html>\n<html><head><title>%d %s</title></head><body><h1>%d %s</h1><p>%s</p></body></html>\n",
+			problem.Status, problem.Title, problem.Status, problem.Title, html.EscapeString(problem.Detail))
+		return
+	}
+	c.SetHeader("Content-Type", "application/problem+json")
+	c.Status(problem.Status)
+	_ = json.NewEncoder(c.Writer).Encode(problem)
+}
+
+// hand the last error of the context to the Application.ErrorHandler, unless a response was written
+func renderErrors(c *Context) {
+	if len(c.errors) == 0 || c.Writer.Written() || c.app.ErrorHandler == nil {
+		return
+	}
+	c.app.ErrorHandler(c, c.errors.Last())
+}
+
+// middleware rendering the errors of the handlers after it,
+// so middlewares before it see the final status. without it errors are rendered once the chain returned
+func ErrorRenderer() HandlerFunc {
+	return func(c *Context) {
+		c.Next()
+		renderErrors(c)
+	}
+}
+
+// default NoRoute handler
+func notFound(c *Context) {
+	c.Error(NewHTTPError(http.StatusNotFound, "404 NOT FOUND: "+c.Path))
+}
+
+// default NoMethod handler
+func methodNotAllowed(c *Context) {
+	c.Error(NewHTTPError(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: "+c.Path))
+}
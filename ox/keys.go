@@ -0,0 +1,123 @@
+package ox
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// request context exposing the values of Context.Set through Value
+type keysContext struct {
+	context.Context
+	mu   *sync.RWMutex
+	keys map[string]interface{}
+}
+
+func (kc *keysContext) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		kc.mu.RLock()
+		value, exists := kc.keys[k]
+		kc.mu.RUnlock()
+		if exists {
+			return value
+		}
+	}
+	return kc.Context.Value(key)
+}
+
+// store a value for the request, also visible through c.Req.Context().Value(key)
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	if c.keys == nil {
+		c.keys = make(map[string]interface{})
+		c.Req = c.Req.WithContext(&keysContext{Context: c.Req.Context(), mu: &c.mu, keys: c.keys})
+	}
+	c.keys[key] = value
+	c.mu.Unlock()
+}
+
+// get a value stored by Set
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	value, exists = c.keys[key]
+	c.mu.RUnlock()
+	return
+}
+
+// get a value stored by Set, panics if missing
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic("key \"" + key + "\" does not exist")
+}
+
+func (c *Context) GetString(key string) (s string) {
+	if value, ok := c.Get(key); ok {
+		s, _ = value.(string)
+	}
+	return
+}
+
+func (c *Context) GetBool(key string) (b bool) {
+	if value, ok := c.Get(key); ok {
+		b, _ = value.(bool)
+	}
+	return
+}
+
+func (c *Context) GetInt(key string) (i int) {
+	if value, ok := c.Get(key); ok {
+		i, _ = value.(int)
+	}
+	return
+}
+
+func (c *Context) GetInt64(key string) (i int64) {
+	if value, ok := c.Get(key); ok {
+		i, _ = value.(int64)
+	}
+	return
+}
+
+func (c *Context) GetUint64(key string) (i uint64) {
+	if value, ok := c.Get(key); ok {
+		i, _ = value.(uint64)
+	}
+	return
+}
+
+func (c *Context) GetFloat64(key string) (f float64) {
+	if value, ok := c.Get(key); ok {
+		f, _ = value.(float64)
+	}
+	return
+}
+
+func (c *Context) GetTime(key string) (t time.Time) {
+	if value, ok := c.Get(key); ok {
+		t, _ = value.(time.Time)
+	}
+	return
+}
+
+func (c *Context) GetDuration(key string) (d time.Duration) {
+	if value, ok := c.Get(key); ok {
+		d, _ = value.(time.Duration)
+	}
+	return
+}
+
+func (c *Context) GetStringSlice(key string) (ss []string) {
+	if value, ok := c.Get(key); ok {
+		ss, _ = value.([]string)
+	}
+	return
+}
+
+func (c *Context) GetStringMap(key string) (sm map[string]interface{}) {
+	if value, ok := c.Get(key); ok {
+		sm, _ = value.(map[string]interface{})
+	}
+	return
+}
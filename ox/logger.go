@@ -0,0 +1,97 @@
+package ox
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	logger "ox-web/log"
+)
+
+type LoggerConfig struct {
+	// defaults to logger.Default()
+	Logger *logger.Logger
+	// paths that are never logged
+	SkipPaths []string
+	// paths logged once every n requests, e.g. {"/health": 100}
+	SamplePaths map[string]uint64
+}
+
+// middleware writing one json line per request
+func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
+	out := conf.Logger
+	if out == nil {
+		out = logger.Default()
+	}
+	skip := make(map[string]bool, len(conf.SkipPaths))
+	for _, path := range conf.SkipPaths {
+		skip[path] = true
+	}
+	// 计数器在创建时分配好, 请求时只做原子加法
+	type sampler struct {
+		every uint64
+		count uint64
+	}
+	samplers := make(map[string]*sampler, len(conf.SamplePaths))
+	for path, every := range conf.SamplePaths {
+		samplers[path] = &sampler{every: every}
+	}
+	return func(c *Context) {
+		path := c.Path
+		if skip[path] {
+			c.Next()
+			return
+		}
+		if s, ok := samplers[path]; ok && s.every > 1 {
+			if atomic.AddUint64(&s.count, 1)%s.every != 1 {
+				c.Next()
+				return
+			}
+		}
+		start := time.Now()
+		c.Next()
+		renderErrors(c) // log the status of the rendered error
+		latency := time.Since(start)
+
+		status := c.Writer.Status()
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+		var entry *logger.Entry
+		switch {
+		case status >= http.StatusInternalServerError:
+			entry = out.ERROR()
+		case status >= http.StatusBadRequest:
+			entry = out.WARN()
+		default:
+			entry = out.INFO()
+		}
+		_ = entry.
+			Str("method", c.Method).
+			Str("path", path).
+			Str("route", c.FullPath()).
+			Int("status", int64(status)).
+			Int("bytes", int64(size)).
+			Float("latency_ms", float64(latency)/float64(time.Millisecond)).
+			Str("client_ip", c.ClientIP()).
+			Str("user_agent", c.Req.UserAgent()).
+			Str("request_id", requestID(c)).
+			Send()
+	}
+}
+
+// id of the RequestID middleware, falling back to the X-Request-ID headers
+func requestID(c *Context) string {
+	if id := c.RequestID(); id != "" {
+		return id
+	}
+	if id := c.Writer.Header().Get(RequestIDHeader); id != "" {
+		return id
+	}
+	return c.Req.Header.Get(RequestIDHeader)
+}
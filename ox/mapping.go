@@ -0,0 +1,283 @@
+package ox
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// values looked up by the name in a struct tag
+type valueSource interface {
+	values(name string) ([]string, bool)
+}
+
+type formSource map[string][]string
+
+func (s formSource) values(name string) ([]string, bool) {
+	vs, ok := s[name]
+	return vs, ok
+}
+
+// header names are canonicalized, so `header:"x-request-id"` finds X-Request-Id
+type headerSource map[string][]string
+
+func (s headerSource) values(name string) ([]string, bool) {
+	vs, ok := s[textproto.CanonicalMIMEHeaderKey(name)]
+	return vs, ok
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// fill the fields of ptr from src by their tag.
+// fields without the tag use their name, untagged structs are walked into and `tag:"-"` is skipped.
+// missing values take the `default:"..."` tag, comma separated for slices.
+// times are parsed with `time_format:"2006-01-02"` (RFC 3339 by default, "unix" for seconds) and `time_utc:"1"`
+func mapValues(ptr interface{}, src valueSource, tag string) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("binding: expects a non-nil pointer")
+	}
+	v = v.Elem()
+	switch v.Kind() {
+	case reflect.Struct:
+		return mapStruct(v, src, tag)
+	case reflect.Map:
+		return mapMap(v, src)
+	}
+	return fmt.Errorf("binding: unsupported type %s", v.Type())
+}
+
+// map[string]string or map[string][]string, only filled from forms and headers
+func mapMap(v reflect.Value, src valueSource) error {
+	var all map[string][]string
+	switch s := src.(type) {
+	case formSource:
+		all = s
+	case headerSource:
+		all = s
+	}
+	t := v.Type()
+	if t.Key().Kind() != reflect.String {
+		return fmt.Errorf("binding: unsupported type %s", t)
+	}
+	if v.IsNil() {
+		v.Set(reflect.MakeMap(t))
+	}
+	for key, vs := range all {
+		switch {
+		case t.Elem().Kind() == reflect.String && len(vs) > 0:
+			v.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(vs[0]))
+		case t.Elem() == reflect.TypeOf([]string(nil)):
+			v.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(vs))
+		case t.Elem().Kind() != reflect.String:
+			return fmt.Errorf("binding: unsupported type %s", t)
+		}
+	}
+	return nil
+}
+
+func mapStruct(v reflect.Value, src valueSource, tag string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous { // unexported
+			continue
+		}
+		fv := v.Field(i)
+		name := sf.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		if j := strings.IndexByte(name, ','); j >= 0 {
+			name = name[:j]
+		}
+		if name == "" {
+			if ft := derefType(sf.Type); ft.Kind() == reflect.Struct && !isScalar(ft) {
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						if !fv.CanSet() { // nil pointer to an unexported struct, skipped like encoding/json
+							continue
+						}
+						fv.Set(reflect.New(ft))
+					}
+					fv = fv.Elem()
+				}
+				if err := mapStruct(fv, src, tag); err != nil {
+					return err
+				}
+				continue
+			}
+			name = sf.Name
+		}
+		if !fv.CanSet() { // embedded unexported non-struct type
+			continue
+		}
+		vs, ok := src.values(name)
+		if !ok || len(vs) == 0 {
+			def, hasDefault := sf.Tag.Lookup("default")
+			if !hasDefault {
+				continue
+			}
+			vs = []string{def}
+			if derefType(sf.Type).Kind() == reflect.Slice {
+				vs = strings.Split(def, ",")
+			}
+		}
+		if err := setField(fv, sf, vs); err != nil {
+			return fmt.Errorf("binding %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// types set from a single value instead of being walked into
+func isScalar(t reflect.Type) bool {
+	return t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func setField(v reflect.Value, sf reflect.StructField, vs []string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := setField(elem.Elem(), sf, vs); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 { // []byte
+			v.SetBytes([]byte(vs[0]))
+			return nil
+		}
+		slice := reflect.MakeSlice(v.Type(), len(vs), len(vs))
+		for i, s := range vs {
+			if err := setField(slice.Index(i), sf, []string{s}); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	return setValue(v, sf, vs[0])
+}
+
+func setValue(v reflect.Value, sf reflect.StructField, s string) error {
+	switch v.Type() {
+	case timeType:
+		t, err := parseTime(sf, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			v.SetUint(0)
+			return nil
+		}
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+func parseTime(sf reflect.StructField, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	format := sf.Tag.Get("time_format")
+	var t time.Time
+	var err error
+	switch format {
+	case "unix", "unixnano":
+		var i int64
+		if i, err = strconv.ParseInt(s, 10, 64); err != nil {
+			return t, err
+		}
+		if format == "unix" {
+			t = time.Unix(i, 0)
+		} else {
+			t = time.Unix(0, i)
+		}
+	case "":
+		t, err = time.Parse(time.RFC3339, s)
+	default:
+		t, err = time.ParseInLocation(format, s, time.Local)
+	}
+	if err != nil {
+		return t, err
+	}
+	if utc, _ := strconv.ParseBool(sf.Tag.Get("time_utc")); utc {
+		t = t.UTC()
+	}
+	return t, nil
+}
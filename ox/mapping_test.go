@@ -0,0 +1,145 @@
+package ox
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type unexportedInner struct {
+	Page int `form:"page"`
+}
+
+type unexportedInt int
+
+func TestMapValuesUnexportedEmbedded(t *testing.T) {
+	var v struct {
+		*unexportedInner
+		unexportedInt
+		Name string `form:"name"`
+	}
+	src := formSource{"name": {"a"}, "page": {"2"}, "unexportedInt": {"3"}}
+	if err := mapValues(&v, src, "form"); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "a" || v.unexportedInner != nil || v.unexportedInt != 0 {
+		t.Fatalf("got %+v", v)
+	}
+
+	var w struct {
+		unexportedInner
+		Name string `form:"name"`
+	}
+	if err := mapValues(&w, src, "form"); err != nil {
+		t.Fatal(err)
+	}
+	if w.Page != 2 || w.Name != "a" {
+		t.Fatalf("got %+v", w)
+	}
+}
+
+type mappingPage struct {
+	Page int `form:"page" default:"1"`
+}
+
+type mappingTarget struct {
+	mappingPage
+	Name     string        `form:"name"`
+	Tags     []string      `form:"tag"`
+	IDs      []int         `form:"ids" default:"1,2"`
+	Ptr      *int          `form:"ptr"`
+	PtrSlice *[]string     `form:"ps"`
+	Day      time.Time     `form:"day" time_format:"2006-01-02" time_utc:"1"`
+	Stamp    time.Time     `form:"stamp"`
+	Unix     time.Time     `form:"unix" time_format:"unix"`
+	Wait     time.Duration `form:"wait"`
+	Ratio    float32       `form:"ratio"`
+	On       bool          `form:"on"`
+	Small    uint8         `form:"small"`
+	Skip     string        `form:"-"`
+	Untagged string
+	Nested   struct {
+		Deep string `form:"deep"`
+	}
+}
+
+func intPtr(i int) *int { return &i }
+
+func TestMapValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   formSource
+		check func(m mappingTarget) bool
+	}{
+		{"string", formSource{"name": {"a", "b"}}, func(m mappingTarget) bool { return m.Name == "a" }},
+		{"slice", formSource{"tag": {"x", "y"}}, func(m mappingTarget) bool { return reflect.DeepEqual(m.Tags, []string{"x", "y"}) }},
+		{"slice default", formSource{}, func(m mappingTarget) bool { return reflect.DeepEqual(m.IDs, []int{1, 2}) }},
+		{"slice value over default", formSource{"ids": {"7"}}, func(m mappingTarget) bool { return reflect.DeepEqual(m.IDs, []int{7}) }},
+		{"embedded default", formSource{}, func(m mappingTarget) bool { return m.Page == 1 }},
+		{"embedded value", formSource{"page": {"3"}}, func(m mappingTarget) bool { return m.Page == 3 }},
+		{"pointer", formSource{"ptr": {"5"}}, func(m mappingTarget) bool { return m.Ptr != nil && *m.Ptr == 5 }},
+		{"pointer missing", formSource{}, func(m mappingTarget) bool { return m.Ptr == nil }},
+		{"pointer to slice", formSource{"ps": {"a", "b"}}, func(m mappingTarget) bool { return m.PtrSlice != nil && len(*m.PtrSlice) == 2 }},
+		{"time_format", formSource{"day": {"2020-01-02"}}, func(m mappingTarget) bool {
+			return m.Day.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.Local)) && m.Day.Location() == time.UTC
+		}},
+		{"rfc3339", formSource{"stamp": {"2020-01-02T03:04:05Z"}}, func(m mappingTarget) bool { return m.Stamp.Unix() == 1577934245 }},
+		{"unix", formSource{"unix": {"10"}}, func(m mappingTarget) bool { return m.Unix.Unix() == 10 }},
+		{"duration", formSource{"wait": {"1m30s"}}, func(m mappingTarget) bool { return m.Wait == 90*time.Second }},
+		{"float", formSource{"ratio": {"0.5"}}, func(m mappingTarget) bool { return m.Ratio == 0.5 }},
+		{"bool", formSource{"on": {"true"}}, func(m mappingTarget) bool { return m.On }},
+		{"skipped", formSource{"Skip": {"x"}, "-": {"x"}}, func(m mappingTarget) bool { return m.Skip == "" }},
+		{"field name", formSource{"Untagged": {"u"}}, func(m mappingTarget) bool { return m.Untagged == "u" }},
+		{"nested struct", formSource{"deep": {"d"}}, func(m mappingTarget) bool { return m.Nested.Deep == "d" }},
+	}
+	for _, tt := range tests {
+		var m mappingTarget
+		if err := mapValues(&m, tt.src, "form"); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !tt.check(m) {
+			t.Errorf("%s: got %+v", tt.name, m)
+		}
+	}
+}
+
+func TestMapValuesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  formSource
+	}{
+		{"int", formSource{"ptr": {"x"}}},
+		{"overflow", formSource{"small": {"256"}}},
+		{"slice element", formSource{"ids": {"1", "x"}}},
+		{"time", formSource{"day": {"02/01/2020"}}},
+		{"duration", formSource{"wait": {"soon"}}},
+		{"bool", formSource{"on": {"maybe"}}},
+	}
+	for _, tt := range tests {
+		var m mappingTarget
+		if err := mapValues(&m, tt.src, "form"); err == nil {
+			t.Errorf("%s: got no error", tt.name)
+		}
+	}
+	var notPointer mappingTarget
+	if err := mapValues(notPointer, formSource{}, "form"); err == nil {
+		t.Error("non-pointer: got no error")
+	}
+}
+
+func TestMapValuesMap(t *testing.T) {
+	src := formSource{"a": {"1", "2"}}
+	var single map[string]string
+	if err := mapValues(&single, src, "form"); err != nil || single["a"] != "1" {
+		t.Errorf("map[string]string: got %v, %v", single, err)
+	}
+	var multi map[string][]string
+	if err := mapValues(&multi, src, "form"); err != nil || len(multi["a"]) != 2 {
+		t.Errorf("map[string][]string: got %v, %v", multi, err)
+	}
+	var ints map[string]int
+	if err := mapValues(&ints, src, "form"); err == nil {
+		t.Error("map[string]int: got no error")
+	}
+}
@@ -0,0 +1,266 @@
+package ox
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// encode v as msgpack. structs are maps keyed by the `msgpack` tag, the `json` tag or the field name,
+// ",omitempty" and "-" work as in encoding/json. times are RFC 3339 strings
+func encodeMsgPack(v interface{}) ([]byte, error) {
+	return appendMsgPack(make([]byte, 0, 64), reflect.ValueOf(v), 0)
+}
+
+// nesting allowed before giving up, values referencing themselves would recurse forever
+const msgPackMaxDepth = 1000
+
+func appendMsgPack(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	if !v.IsValid() {
+		return append(b, 0xc0), nil
+	}
+	if depth > msgPackMaxDepth {
+		return nil, fmt.Errorf("msgpack: %s nested deeper than %d, cyclic value?", v.Type(), msgPackMaxDepth)
+	}
+	switch v.Type() {
+	case timeType:
+		return appendMsgPackString(b, v.Interface().(time.Time).Format(time.RFC3339Nano)), nil
+	case durationType:
+		return appendMsgPackInt(b, v.Int()), nil
+	}
+	if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface && v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return appendMsgPackString(b, string(text)), nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		return appendMsgPack(b, v.Elem(), depth+1)
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgPackInt(b, v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendMsgPackUint(b, v.Uint()), nil
+	case reflect.Float32:
+		b = append(b, 0xca)
+		return appendUint32(b, math.Float32bits(float32(v.Float()))), nil
+	case reflect.Float64:
+		b = append(b, 0xcb)
+		return appendUint64(b, math.Float64bits(v.Float())), nil
+	case reflect.String:
+		return appendMsgPackString(b, v.String()), nil
+	case reflect.Slice:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return appendMsgPackBinary(b, v.Bytes()), nil
+		}
+		fallthrough
+	case reflect.Array:
+		n := v.Len()
+		b = appendMsgPackHeader(b, n, 0x90, 0xdc)
+		var err error
+		for i := 0; i < n; i++ {
+			if b, err = appendMsgPack(b, v.Index(i), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return append(b, 0xc0), nil
+		}
+		keys := v.MapKeys()
+		if v.Type().Key().Kind() == reflect.String { // stable output
+			sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		}
+		b = appendMsgPackHeader(b, len(keys), 0x80, 0xde)
+		var err error
+		for _, key := range keys {
+			if b, err = appendMsgPack(b, key, depth+1); err != nil {
+				return nil, err
+			}
+			if b, err = appendMsgPack(b, v.MapIndex(key), depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		return appendMsgPackStruct(b, v, depth)
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type %s", v.Type())
+}
+
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+type msgPackField struct {
+	name  string
+	value reflect.Value
+}
+
+func appendMsgPackStruct(b []byte, v reflect.Value, depth int) ([]byte, error) {
+	fields, err := msgPackFields(nil, v, depth)
+	if err != nil {
+		return nil, err
+	}
+	b = appendMsgPackHeader(b, len(fields), 0x80, 0xde)
+	for _, f := range fields {
+		b = appendMsgPackString(b, f.name)
+		if b, err = appendMsgPack(b, f.value, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// exported fields of v, the fields of untagged embedded structs are promoted
+func msgPackFields(fields []msgPackField, v reflect.Value, depth int) ([]msgPackField, error) {
+	if depth > msgPackMaxDepth {
+		return nil, fmt.Errorf("msgpack: %s nested deeper than %d, cyclic value?", v.Type(), msgPackMaxDepth)
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, ok := sf.Tag.Lookup("msgpack")
+		if !ok {
+			tag = sf.Tag.Get("json")
+		}
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name, opts = tag[:j], tag[j:]
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				var err error
+				if fields, err = msgPackFields(fields, fv, depth+1); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if strings.Contains(opts, ",omitempty") && isEmpty(fv) {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fields = append(fields, msgPackField{name: name, value: fv})
+	}
+	return fields, nil
+}
+
+// fixed header for small sizes, 16 or 32 bit length otherwise. arrays and maps share the layout
+func appendMsgPackHeader(b []byte, n int, fix byte, code16 byte) []byte {
+	switch {
+	case n < 16:
+		return append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(b, code16), uint16(n))
+	}
+	return appendUint32(append(b, code16+1), uint32(n))
+}
+
+func appendMsgPackString(b []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = appendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = appendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+func appendMsgPackBinary(b []byte, data []byte) []byte {
+	n := len(data)
+	switch {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = appendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = appendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, data...)
+}
+
+// smallest encoding of i
+func appendMsgPackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return appendMsgPackUint(b, uint64(i))
+	case i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return appendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return appendUint32(append(b, 0xd2), uint32(i))
+	}
+	return appendUint64(append(b, 0xd3), uint64(i))
+}
+
+func appendMsgPackUint(b []byte, u uint64) []byte {
+	switch {
+	case u <= 0x7f:
+		return append(b, byte(u))
+	case u <= math.MaxUint8:
+		return append(b, 0xcc, byte(u))
+	case u <= math.MaxUint16:
+		return appendUint16(append(b, 0xcd), uint16(u))
+	case u <= math.MaxUint32:
+		return appendUint32(append(b, 0xce), uint32(u))
+	}
+	return appendUint64(append(b, 0xcf), u)
+}
+
+func appendUint16(b []byte, u uint16) []byte {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], u)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, u uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], u)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, u uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], u)
+	return append(b, buf[:]...)
+}
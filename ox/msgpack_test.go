@@ -0,0 +1,124 @@
+package ox
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+)
+
+type msgPackBase struct {
+	ID int `json:"id"`
+}
+
+type msgPackItem struct {
+	msgPackBase
+	Name  string `msgpack:"n" json:"name"`
+	Note  string `json:"note,omitempty"`
+	Skip  string `json:"-"`
+	Flag  bool
+	inner int
+}
+
+type msgPackNode struct {
+	Next *msgPackNode
+}
+
+type msgPackLoop struct {
+	*msgPackLoop
+}
+
+func TestEncodeMsgPack(t *testing.T) {
+	map15 := map[string]int{}
+	map16 := map[string]int{}
+	for i := 0; i < 16; i++ {
+		if i < 15 {
+			map15[string(rune('a'+i))] = 0
+		}
+		map16[string(rune('a'+i))] = 0
+	}
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string // hex, only the header when prefix is set
+		whole bool
+	}{
+		{"nil", nil, "c0", true},
+		{"bool", []bool{true, false}, "92c3c2", true},
+		{"fixint", 127, "7f", true},
+		{"uint8", 128, "cc80", true},
+		{"uint8 max", 255, "ccff", true},
+		{"uint16", 256, "cd0100", true},
+		{"uint16 max", 65535, "cdffff", true},
+		{"uint32", 65536, "ce00010000", true},
+		{"uint64", uint64(1) << 32, "cf0000000100000000", true},
+		{"negative fixint", -32, "e0", true},
+		{"int8", -33, "d0df", true},
+		{"int8 min", -128, "d080", true},
+		{"int16", -129, "d1ff7f", true},
+		{"int32", -32769, "d2ffff7fff", true},
+		{"float32", float32(1.5), "ca3fc00000", true},
+		{"float64", 1.5, "cb3ff8000000000000", true},
+		{"fixstr", "ab", "a26162", true},
+		{"fixstr max", strings.Repeat("a", 31), "bf61", false},
+		{"str8", strings.Repeat("a", 32), "d92061", false},
+		{"str16", strings.Repeat("a", 256), "da010061", false},
+		{"bin", []byte{1, 2}, "c4020102", true},
+		{"nil slice", []int(nil), "c0", true},
+		{"fixarray max", make([]int, 15), "9f00", false},
+		{"array16", make([]int, 16), "dc001000", false},
+		{"fixmap max", map15, "8fa161", false},
+		{"map16", map16, "de0010a161", false},
+		{"sorted keys", map[string]int{"b": 2, "a": 1}, "82a16101a16202", true},
+		{"duration", time.Second, "ce3b9aca00", true},
+		{"time", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), hex.EncodeToString(append([]byte{0xb4}, "2020-01-02T03:04:05Z"...)), true},
+		// embedded fields promoted, msgpack tag before json, omitempty, "-" and unexported skipped
+		{"struct", msgPackItem{msgPackBase: msgPackBase{ID: 1}, Name: "x", Skip: "s", inner: 2},
+			"83a2696401a16ea178a4466c6167c2", true},
+		{"struct omitempty set", &msgPackItem{Note: "y"},
+			"84a2696400a16ea0a46e6f7465a179a4466c6167c2", true},
+		{"nil embedded pointer", msgPackLoop{}, "80", true},
+	}
+	for _, tt := range tests {
+		data, err := encodeMsgPack(tt.value)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		got := hex.EncodeToString(data)
+		if tt.whole && got != tt.want || !tt.whole && !strings.HasPrefix(got, tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeMsgPackCycle(t *testing.T) {
+	n := &msgPackNode{}
+	n.Next = n
+	if _, err := encodeMsgPack(n); err == nil {
+		t.Error("pointer cycle: got no error")
+	}
+	l := &msgPackLoop{}
+	l.msgPackLoop = l
+	if _, err := encodeMsgPack(l); err == nil {
+		t.Error("embedded cycle: got no error")
+	}
+	m := map[string]interface{}{}
+	m["m"] = m
+	if _, err := encodeMsgPack(m); err == nil {
+		t.Error("map cycle: got no error")
+	}
+	s := []interface{}{nil}
+	s[0] = s
+	if _, err := encodeMsgPack(s); err == nil {
+		t.Error("slice cycle: got no error")
+	}
+	// deep but finite values still encode
+	deep := &msgPackNode{}
+	for i := 0; i < 100; i++ {
+		deep = &msgPackNode{Next: deep}
+	}
+	if _, err := encodeMsgPack(deep); err != nil {
+		t.Errorf("deep value: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package ox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+)
+
+// handles a panic recovered by the Recovery middleware
+type RecoveryFunc func(c *Context, err interface{})
+
+// middleware turning panics of the handlers after it into 500 responses
+func Recovery() HandlerFunc {
+	return RecoveryWithHandler(defaultRecovery)
+}
+
+// like Recovery with a custom callback writing the response, the panic is logged before
+func RecoveryWithHandler(handle RecoveryFunc) HandlerFunc {
+	return func(c *Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+			stack := debug.Stack()
+			if hp, ok := err.(*handlerPanic); ok { // raised again by Timeout
+				err, stack = hp.value, hp.stack
+			}
+			brokenPipe := isBrokenPipe(err)
+			c.Logger().ERROR().
+				Str("method", c.Method).
+				Str("path", c.Path).
+				Str("panic", fmt.Sprint(err)).
+				Bool("broken_pipe", brokenPipe).
+				Str("stack", string(stack)).
+				Msg("panic recovered")
+			if brokenPipe { // 连接已经断开, 无法再写响应
+				c.Abort()
+				return
+			}
+			handle(c, err)
+		}()
+		c.Next()
+	}
+}
+
+func defaultRecovery(c *Context, err interface{}) {
+	c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("panic: %v", err))
+}
+
+// the client went away while the response was written
+func isBrokenPipe(err interface{}) bool {
+	e, ok := err.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(e, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	msg := strings.ToLower(syscallErr.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
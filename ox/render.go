@@ -0,0 +1,299 @@
+package ox
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// response body in some format, written by Context.Render after the content type and status
+type Render interface {
+	ContentType() string
+	Render(w http.ResponseWriter) error
+}
+
+// write r with the status code. render errors are attached with Context.Error,
+// so a failure before the first byte is still replied by the ErrorHandler
+func (c *Context) Render(code int, r Render) {
+	if ct := r.ContentType(); ct != "" {
+		c.SetHeader("Content-Type", ct)
+	}
+	c.Status(code)
+	if !bodyAllowed(code) {
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	if err := r.Render(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+// 1xx, 204 and 304 responses have no body
+func bodyAllowed(code int) bool {
+	return (code < 100 || code > 199) && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+type JSONRender struct {
+	Data interface{}
+}
+
+func (JSONRender) ContentType() string { return "application/json" }
+
+func (r JSONRender) Render(w http.ResponseWriter) error {
+	return json.NewEncoder(w).Encode(r.Data)
+}
+
+// json indented with 4 spaces, for humans
+type IndentedJSONRender struct {
+	Data interface{}
+}
+
+func (IndentedJSONRender) ContentType() string { return "application/json" }
+
+func (r IndentedJSONRender) Render(w http.ResponseWriter) error {
+	data, err := json.MarshalIndent(r.Data, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// json with every non-ascii character escaped as \uXXXX
+type AsciiJSONRender struct {
+	Data interface{}
+}
+
+func (AsciiJSONRender) ContentType() string { return "application/json" }
+
+func (r AsciiJSONRender) Render(w http.ResponseWriter) error {
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for len(data) > 0 {
+		c, size := utf8.DecodeRune(data)
+		data = data[size:]
+		if c < utf8.RuneSelf {
+			buf.WriteByte(byte(c))
+			continue
+		}
+		if c > 0xffff { // utf-16 surrogate pair
+			c -= 0x10000
+			fmt.Fprintf(&buf, `\u%04x\u%04x`, 0xd800+(c>>10), 0xdc00+(c&0x3ff))
+			continue
+		}
+		fmt.Fprintf(&buf, `\u%04x`, c)
+	}
+	buf.WriteByte('\n')
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// json without escaping <, > and & for html
+type PureJSONRender struct {
+	Data interface{}
+}
+
+func (PureJSONRender) ContentType() string { return "application/json" }
+
+func (r PureJSONRender) Render(w http.ResponseWriter) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(r.Data)
+}
+
+// javascript identifiers separated by dots, such as cb or jQuery.cb_1
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][0-9A-Za-z_$]*(\.[A-Za-z_$][0-9A-Za-z_$]*)*$`)
+
+// json wrapped in a call of Callback, check it with ValidJSONPCallback first
+type JSONPRender struct {
+	Callback string
+	Data     interface{}
+}
+
+func (JSONPRender) ContentType() string { return "application/javascript" }
+
+func (r JSONPRender) Render(w http.ResponseWriter) error {
+	if !ValidJSONPCallback(r.Callback) {
+		return fmt.Errorf("jsonp: invalid callback %q", r.Callback)
+	}
+	data, err := json.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	// the leading comment keeps the body from starting with bytes the client controls
+	_, err = fmt.Fprintf(w, "/**/%s(%s);", r.Callback, data)
+	return err
+}
+
+func ValidJSONPCallback(callback string) bool {
+	return len(callback) <= 128 && jsonpCallback.MatchString(callback)
+}
+
+type XMLRender struct {
+	Data interface{}
+}
+
+func (XMLRender) ContentType() string { return "application/xml; charset=utf-8" }
+
+func (r XMLRender) Render(w http.ResponseWriter) error {
+	data, err := xml.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append([]byte(xml.Header), data...))
+	return err
+}
+
+// yaml document, fields are named by their json tags, see encodeYAML
+type YAMLRender struct {
+	Data interface{}
+}
+
+func (YAMLRender) ContentType() string { return "application/yaml; charset=utf-8" }
+
+func (r YAMLRender) Render(w http.ResponseWriter) error {
+	data, err := encodeYAML(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// msgpack, fields are named by their msgpack or json tags, see encodeMsgPack
+type MsgPackRender struct {
+	Data interface{}
+}
+
+func (MsgPackRender) ContentType() string { return "application/msgpack" }
+
+func (r MsgPackRender) Render(w http.ResponseWriter) error {
+	data, err := encodeMsgPack(r.Data)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+type StringRender struct {
+	Format string
+	Values []interface{}
+}
+
+func (StringRender) ContentType() string { return "text/plain" }
+
+func (r StringRender) Render(w http.ResponseWriter) error {
+	_, err := fmt.Fprintf(w, r.Format, r.Values...)
+	return err
+}
+
+// raw bytes, no content type is set when Type is empty
+type DataRender struct {
+	Type string
+	Data []byte
+}
+
+func (r DataRender) ContentType() string { return r.Type }
+
+func (r DataRender) Render(w http.ResponseWriter) error {
+	_, err := w.Write(r.Data)
+	return err
+}
+
+// body copied from Reader, with a Content-Length when Length is not negative
+type ReaderRender struct {
+	Type    string
+	Length  int64
+	Reader  io.Reader
+	Headers map[string]string
+}
+
+func (r ReaderRender) ContentType() string { return r.Type }
+
+func (r ReaderRender) Render(w http.ResponseWriter) error {
+	header := w.Header()
+	for key, value := range r.Headers {
+		header.Set(key, value)
+	}
+	if r.Length >= 0 {
+		header.Set("Content-Length", strconv.FormatInt(r.Length, 10))
+	}
+	_, err := io.Copy(w, r.Reader)
+	return err
+}
+
+// template rendered into a buffer before anything is sent, a failure can still reply 500
+type HTMLRender struct {
+	Template *template.Template
+	Name     string
+	Data     interface{}
+}
+
+func (HTMLRender) ContentType() string { return "text/html" }
+
+func (r HTMLRender) Render(w http.ResponseWriter) error {
+	if r.Template == nil {
+		return fmt.Errorf("html %s: no templates loaded", r.Name)
+	}
+	var buf bytes.Buffer
+	if err := r.Template.ExecuteTemplate(&buf, r.Name, r.Data); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+var (
+	renderersMu sync.RWMutex
+	renderers   = map[string]func(data interface{}) Render{
+		"application/json":        func(data interface{}) Render { return JSONRender{Data: data} },
+		"application/xml":         func(data interface{}) Render { return XMLRender{Data: data} },
+		"text/xml":                func(data interface{}) Render { return XMLRender{Data: data} },
+		"application/yaml":        func(data interface{}) Render { return YAMLRender{Data: data} },
+		"application/x-yaml":      func(data interface{}) Render { return YAMLRender{Data: data} },
+		"application/msgpack":     func(data interface{}) Render { return MsgPackRender{Data: data} },
+		"application/x-msgpack":   func(data interface{}) Render { return MsgPackRender{Data: data} },
+		"application/vnd.msgpack": func(data interface{}) Render { return MsgPackRender{Data: data} },
+	}
+)
+
+// make a format available to Context.Negotiate under its media type
+func RegisterRender(mediaType string, fn func(data interface{}) Render) {
+	renderersMu.Lock()
+	defer renderersMu.Unlock()
+	renderers[strings.ToLower(mediaType)] = fn
+}
+
+// render data in the first format of the Accept header that has a registered Render, json otherwise
+func (c *Context) Negotiate(code int, data interface{}) {
+	c.Render(code, negotiate(c.Req.Header.Get("Accept"), data))
+}
+
+func negotiate(accept string, data interface{}) Render {
+	renderersMu.RLock()
+	defer renderersMu.RUnlock()
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || params["q"] == "0" {
+			continue
+		}
+		if fn, ok := renderers[mediaType]; ok {
+			return fn(data)
+		}
+	}
+	return JSONRender{Data: data}
+}
@@ -0,0 +1,64 @@
+package ox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	logger "ox-web/log"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	// Context key of the request id
+	RequestIDKey = "request_id"
+)
+
+// middleware reading X-Request-ID or generating one, the id is stored on the Context,
+// echoed in the response and added to the logger returned by Context.Logger
+func RequestID() HandlerFunc {
+	return RequestIDWithGenerator(newRequestID)
+}
+
+func RequestIDWithGenerator(generate func() string) HandlerFunc {
+	return func(c *Context) {
+		id := c.Req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = generate()
+		}
+		c.Set(RequestIDKey, id)
+		c.SetHeader(RequestIDHeader, id)
+		ctx := logger.WithContext(c.Req.Context(), logger.Ctx(c.Req.Context()).With(RequestIDKey, id))
+		c.Req = c.Req.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// 128 random bits as hex
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ids from clients are kept when short and printable
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// request id set by the RequestID middleware
+func (c *Context) RequestID() string {
+	return c.GetString(RequestIDKey)
+}
+
+// logger of the request, entries carry the request id when the RequestID middleware ran
+func (c *Context) Logger() *logger.Logger {
+	return logger.Ctx(c.Req.Context())
+}
@@ -0,0 +1,130 @@
+package ox
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// registered route
+type Route struct {
+	Method   string
+	Pattern  string
+	name     string
+	handlers []HandlerFunc
+	app      *Application
+}
+
+// route description returned by Application.Routes
+type RouteInfo struct {
+	Method      string `json:"method"`
+	Pattern     string `json:"pattern"`
+	Handler     string `json:"handler"`
+	Middlewares int    `json:"middlewares"`
+	Name        string `json:"name,omitempty"`
+}
+
+func nameOfFunction(f interface{}) string {
+	return runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()
+}
+
+// registered routes in registration order
+func (app *Application) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0, len(app.routes))
+	for _, route := range app.routes {
+		info := RouteInfo{
+			Method:  route.Method,
+			Pattern: route.Pattern,
+			Name:    route.name,
+		}
+		if n := len(route.handlers); n > 0 {
+			info.Handler = nameOfFunction(route.handlers[n-1])
+			info.Middlewares = n - 1
+		}
+		routes = append(routes, info)
+	}
+	return routes
+}
+
+// write the route table
+func (app *Application) PrintRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "METHOD\tPATTERN\tNAME\tMIDDLEWARES\tHANDLER")
+	for _, route := range app.Routes() {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", route.Method, route.Pattern, route.Name, route.Middlewares, route.Handler)
+	}
+	_ = tw.Flush()
+}
+
+// handler serving the route table as json, for an admin endpoint
+func (app *Application) RoutesHandler() HandlerFunc {
+	return func(c *Context) {
+		c.JSON(http.StatusOK, app.Routes())
+	}
+}
+
+// name the route so its path can be built by Application.URL, panics if the name is taken
+func (r *Route) Name(name string) *Route {
+	if other, ok := r.app.names[name]; ok && other != r {
+		panic(fmt.Sprintf("route name %s is already used by %s %s", name, other.Method, other.Pattern))
+	}
+	delete(r.app.names, r.name)
+	r.name = name
+	r.app.names[name] = r
+	return r
+}
+
+// build the path of the named route, params fill the wildcards in order and are escaped
+// a catch-all value keeps its '/' separators
+func (app *Application) URL(name string, params ...interface{}) (string, error) {
+	route, ok := app.names[name]
+	if !ok {
+		return "", fmt.Errorf("url: no route named %s", name)
+	}
+	return route.url(params...)
+}
+
+func (r *Route) url(params ...interface{}) (string, error) {
+	var b strings.Builder
+	i := 0
+	for _, seg := range strings.Split(r.Pattern[1:], "/") {
+		b.WriteByte('/')
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			b.WriteString(seg)
+			continue
+		}
+		if i >= len(params) {
+			return "", fmt.Errorf("url %s: missing value for %s", r.name, seg)
+		}
+		value := fmt.Sprint(params[i])
+		i++
+		if seg[0] == ':' {
+			if key, expr := splitParam(seg); expr != "" {
+				c, err := newConstraint(expr)
+				if err != nil {
+					return "", fmt.Errorf("url %s: %w", r.name, err)
+				}
+				if !c.match(value) {
+					return "", fmt.Errorf("url %s: value %q does not match :%s<%s>", r.name, value, key, expr)
+				}
+			}
+			b.WriteString(url.PathEscape(value))
+			continue
+		}
+		for j, part := range strings.Split(strings.TrimPrefix(value, "/"), "/") {
+			if j > 0 {
+				b.WriteByte('/')
+			}
+			b.WriteString(url.PathEscape(part))
+		}
+	}
+	if i != len(params) {
+		return "", fmt.Errorf("url %s: got %d values for %d wildcards", r.name, len(params), i)
+	}
+	return b.String(), nil
+}
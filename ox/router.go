@@ -1,69 +1,235 @@
 package ox
 
 import (
-	"log"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 )
 
-// trie tree
+var (
+	ErrInvalidRoute  = errors.New("invalid route")
+	ErrRouteConflict = errors.New("route conflict")
+)
+
+// path parameter
+type Param struct {
+	Key   string
+	Value string
+}
+
+// path parameters in the order they appear in the pattern
+type Params []Param
+
+// value of the first parameter with the given key
+func (ps Params) Get(key string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// value of the first parameter with the given key, "" if missing
+func (ps Params) ByName(key string) string {
+	value, _ := ps.Get(key)
+	return value
+}
+
+type nodeType uint8
+
+const (
+	static nodeType = iota
+	param
+	catchAll
+)
+
+// compressed radix tree
+// 静态节点的 path 是压缩后的公共前缀, 通配符节点的 path 是 ":name<expr>" 或 "*name"
 type node struct {
-	pattern  string
-	part     string
-	children []*node
-	isWild   bool
+	path         string
+	nType        nodeType
+	key          string      // 参数名
+	constraint   *constraint // :param 的约束, 没有约束时为 nil
+	indices      string      // 静态子节点 path 的首字节, 与 children 一一对应
+	children     []*node     // 静态子节点
+	wildChildren []*node     // :param 子节点, 有约束的排在前面
+	catchAll     *node       // *catchAll 子节点
+	pattern      string
+	handlers     []HandlerFunc
 }
 
-// 查找冲突路由
-func (n *node) find(part string) *node {
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// 通配符只能出现在段首, 返回 path 中第一个通配符的位置
+func findWildcard(path string) int {
+	for i := 0; i < len(path); i++ {
+		if (path[i] == ':' || path[i] == '*') && (i == 0 || path[i-1] == '/') {
+			return i
+		}
+	}
+	return -1
+}
+
+// 拆分 ":name<expr>" 为参数名和约束
+func splitParam(seg string) (key string, expr string) {
+	if i := strings.IndexByte(seg, '<'); i > 0 && seg[len(seg)-1] == '>' {
+		return seg[1:i], seg[i+1 : len(seg)-1]
+	}
+	return seg[1:], ""
+}
+
+// 约束相同的 :param 子节点
+func (n *node) findWild(expr string) *node {
+	for _, child := range n.wildChildren {
+		if (child.constraint == nil && expr == "") || (child.constraint != nil && child.constraint.expr == expr) {
 			return child
 		}
 	}
 	return nil
 }
 
-// 匹配路由
-func (n *node) match(part string) []*node {
-	nodes := make([]*node, 0)
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			nodes = append(nodes, child)
+// 有约束的 :param 子节点优先于没有约束的
+func (n *node) addWild(child *node) {
+	i := len(n.wildChildren)
+	if child.constraint != nil {
+		for i > 0 && n.wildChildren[i-1].constraint == nil {
+			i--
 		}
 	}
-	return nodes
+	n.wildChildren = append(n.wildChildren, nil)
+	copy(n.wildChildren[i+1:], n.wildChildren[i:])
+	n.wildChildren[i] = child
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
+// 在 i 处把节点拆成 前缀 + 后缀 两个节点
+func (n *node) split(i int) {
+	child := *n
+	child.path = n.path[i:]
+	*n = node{
+		path:     n.path[:i],
+		nType:    static,
+		indices:  child.path[:1],
+		children: []*node{&child},
+	}
+}
+
+// 插入路由, path 是当前节点之后还未插入的部分
+func (n *node) insert(path string, pattern string, handlers []HandlerFunc) error {
+	if path == "" {
+		if n.pattern != "" {
+			return fmt.Errorf("%w: already registered as %s", ErrRouteConflict, n.pattern)
+		}
 		n.pattern = pattern
-		return
+		n.handlers = handlers
+		return nil
 	}
-	part := parts[height]
-	child := n.find(part)
-	if child == nil {
-		child = &node{part: part, isWild: parts[0] == ":" || parts[0] == "*"}
-		n.children = append(n.children, child)
-		child.insert(pattern, parts, height+1)
-	} else {
-		log.Fatalf("路由冲突: pattern==>%v", pattern)
+	switch path[0] {
+	case ':':
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		seg := path[:end]
+		key, expr := splitParam(seg)
+		child := n.findWild(expr)
+		if child == nil {
+			child = &node{path: seg, nType: param, key: key}
+			if expr != "" {
+				c, err := newConstraint(expr)
+				if err != nil {
+					return err
+				}
+				child.constraint = c
+			}
+			n.addWild(child)
+		} else if child.key != key {
+			return fmt.Errorf("%w: wildcard %s conflicts with existing wildcard %s", ErrRouteConflict, seg, child.path)
+		}
+		return child.insert(path[end:], pattern, handlers)
+	case '*':
+		if n.catchAll == nil {
+			n.catchAll = &node{path: path, nType: catchAll, key: path[1:]}
+		} else if n.catchAll.path != path {
+			return fmt.Errorf("%w: wildcard %s conflicts with existing wildcard %s", ErrRouteConflict, path, n.catchAll.path)
+		}
+		return n.catchAll.insert("", pattern, handlers)
+	}
+	end := findWildcard(path)
+	if end < 0 {
+		end = len(path)
+	}
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] != path[0] {
+			continue
+		}
+		child := n.children[i]
+		l := longestCommonPrefix(child.path, path[:end])
+		if l < len(child.path) {
+			child.split(l)
+		}
+		return child.insert(path[l:], pattern, handlers)
 	}
+	child := &node{path: path[:end], nType: static}
+	n.indices += path[:1]
+	n.children = append(n.children, child)
+	return child.insert(path[end:], pattern, handlers)
 }
 
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(parts[0], "*") {
-		if n.pattern == "" { // 证明还没到树的叶子节点
+// 深度优先查找, 静态 > :param > *catchAll, 高优先级的分支匹配失败时回溯到下一个分支
+// path 是当前节点之后还未匹配的部分, 匹配到的参数追加到 params 中
+func (n *node) search(path string, params *Params) *node {
+	if path == "" {
+		if n.pattern == "" { // 证明还没到叶子节点
 			return nil
 		}
 		return n
 	}
-	part := parts[height]
-	children := n.match(part)
-	for _, child := range children {
-		if result := child.search(parts, height+1); result != nil {
-			return result
+	for i := 0; i < len(n.indices); i++ {
+		if n.indices[i] != path[0] {
+			continue
 		}
+		child := n.children[i]
+		if len(path) >= len(child.path) && path[:len(child.path)] == child.path {
+			if result := child.search(path[len(child.path):], params); result != nil {
+				return result
+			}
+		}
+		break
+	}
+	if len(n.wildChildren) > 0 {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			value := path[:end]
+			for _, child := range n.wildChildren {
+				if child.constraint != nil && !child.constraint.match(value) {
+					continue
+				}
+				k := len(*params)
+				*params = append(*params, Param{Key: child.key, Value: value})
+				if result := child.search(path[end:], params); result != nil {
+					return result
+				}
+				*params = (*params)[:k]
+			}
+		}
+	}
+	if child := n.catchAll; child != nil { // catch-all 一定是叶子节点, 吞掉剩余部分
+		if child.key != "" {
+			*params = append(*params, Param{Key: child.key, Value: path})
+		}
+		return child
 	}
 	return nil
 }
@@ -71,75 +237,131 @@ func (n *node) search(parts []string, height int) *node {
 // router
 // /home/:name 类似
 type router struct {
-	roots    map[string]*node
-	handlers map[string]HandlerFunc
+	roots     map[string]*node
+	maxParams int
 }
 
 func newRouter() *router {
-	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
-	}
+	return &router{roots: make(map[string]*node)}
 }
 
-func parsePattern(pattern string) []string {
-	vs := strings.Split(pattern, "/")
-
-	parts := make([]string, 0)
-	for _, item := range vs {
-		if item != "" {
-			parts = append(parts, item)
-			if item[0] == '*' {
-				break
+// 校验路由格式
+func validatePattern(pattern string) error {
+	if pattern == "" || pattern[0] != '/' {
+		return fmt.Errorf("%w: pattern must begin with '/'", ErrInvalidRoute)
+	}
+	segments := strings.Split(pattern[1:], "/")
+	for i, seg := range segments {
+		if seg != "" && seg[0] == ':' {
+			if lt := strings.IndexByte(seg, '<'); lt >= 0 {
+				if seg[len(seg)-1] != '>' {
+					return fmt.Errorf("%w: unterminated constraint in %s", ErrInvalidRoute, seg)
+				}
+				seg = seg[:lt]
 			}
 		}
+		if seg == ":" {
+			return fmt.Errorf("%w: wildcard must be named", ErrInvalidRoute)
+		}
+		if len(seg) > 1 && strings.ContainsAny(seg[1:], ":*<>") {
+			return fmt.Errorf("%w: wildcard must start a segment in %s", ErrInvalidRoute, seg)
+		}
+		if seg != "" && seg[0] == '*' && i != len(segments)-1 {
+			return fmt.Errorf("%w: catch-all %s must be the last segment", ErrInvalidRoute, seg)
+		}
 	}
-	return parts
+	return nil
 }
 
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
-	parts := parsePattern(pattern)
-	key := method + "-" + pattern
-	if _, ok := r.roots[method]; !ok {
-		r.roots[method] = &node{}
+// number of wildcards in pattern
+func countParams(pattern string) int {
+	return strings.Count(pattern, "/:") + strings.Count(pattern, "/*")
+}
+
+func (r *router) addRoute(method string, pattern string, handlers ...HandlerFunc) error {
+	if err := validatePattern(pattern); err != nil {
+		return fmt.Errorf("%s %s: %w", method, pattern, err)
 	}
-	r.roots[method].insert(pattern, parts, 0)
-	r.handlers[key] = handler
+	root, ok := r.roots[method]
+	if !ok {
+		root = &node{nType: static}
+		r.roots[method] = root
+	}
+	if err := root.insert(pattern, pattern, handlers); err != nil {
+		return fmt.Errorf("%s %s: %w", method, pattern, err)
+	}
+	if n := countParams(pattern); n > r.maxParams {
+		r.maxParams = n
+	}
+	return nil
 }
 
-func (r *router) getRoute(method string, path string) (*node, map[string]string) {
-	searchParts := parsePattern(path)
-	params := make(map[string]string)
+func (r *router) getRoute(method string, path string, params *Params) *node {
 	root, ok := r.roots[method]
 	if !ok {
-		return nil, nil
+		return nil
 	}
-	if n := root.search(searchParts, 0); n != nil {
-		parts := parsePattern(n.pattern)
-		for index, part := range parts {
-			if part[0] == ':' {
-				params[part[1:]] = searchParts[index]
-			}
-			if part[0] == '*' && len(part) > 1 {
-				params[part[1:]] = strings.Join(searchParts[index:], "/")
-				break
-			}
+	return root.search(path, params)
+}
+
+// methods other than reqMethod that have a route for path, used for the Allow header.
+// OPTIONS is listed when it has a route or autoOptions answers it
+func (r *router) allowed(path string, reqMethod string, autoOptions bool) string {
+	methods := make([]string, 0, len(r.roots)+1)
+	hasOptions := false
+	for method := range r.roots {
+		if method == reqMethod {
+			continue
+		}
+		params := make(Params, 0, r.maxParams)
+		if n := r.getRoute(method, path, &params); n != nil {
+			methods = append(methods, method)
+			hasOptions = hasOptions || method == http.MethodOptions
 		}
-		return n, params
 	}
-	return nil, nil
+	if len(methods) == 0 {
+		return ""
+	}
+	if autoOptions && !hasOptions {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
 }
 
 func (r *router) handle(c *Context) {
-	n, params := r.getRoute(c.Method, c.Path)
-	if n != nil {
-		key := c.Method + "-" + n.pattern
-		c.Params = params
-		c.handlers = append(c.handlers, r.handlers[key])
-	} else {
-		c.handlers = append(c.handlers, func(c *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+	app := c.app
+	// declared larger than the application allows, replied before routing
+	if app.MaxBodySize > 0 && c.Req.ContentLength > app.MaxBodySize {
+		c.handlers = app.groupFor(c.Path).combineHandlers([]HandlerFunc{bodyTooLarge})
+		c.Next()
+		return
+	}
+	if n := r.getRoute(c.Method, c.Path, &c.Params); n != nil {
+		c.handlers = n.handlers
+		c.fullPath = n.pattern
+		c.Next()
+		return
+	}
+	group := app.groupFor(c.Path)
+	if app.HandleOPTIONS || app.HandleMethodNotAllowed {
+		if allow := r.allowed(c.Path, c.Method, app.HandleOPTIONS); allow != "" {
+			if c.Method == http.MethodOptions && app.HandleOPTIONS {
+				c.handlers = group.combineHandlers([]HandlerFunc{func(c *Context) {
+					c.SetHeader("Allow", allow)
+					c.Status(http.StatusNoContent)
+				}})
+				c.Next()
+				return
+			}
+			if app.HandleMethodNotAllowed {
+				c.SetHeader("Allow", allow)
+				c.handlers = group.combineHandlers(app.noMethod)
+				c.Next()
+				return
+			}
+		}
 	}
+	c.handlers = group.combineHandlers(app.noRoute)
 	c.Next()
 }
@@ -0,0 +1,279 @@
+package ox
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTree(t *testing.T, patterns ...string) *node {
+	t.Helper()
+	root := &node{nType: static}
+	for _, p := range patterns {
+		if err := root.insert(p, p, nil); err != nil {
+			t.Fatalf("insert %s: %v", p, err)
+		}
+	}
+	return root
+}
+
+func TestNodeSearch(t *testing.T) {
+	root := newTree(t,
+		"/",
+		"/files/new",
+		"/files/:name",
+		"/files/:name/raw",
+		"/files/*path",
+		"/items/:id<int>",
+		"/items/:slug",
+		"/a/:x/c",
+		"/a/*rest",
+		"/users",
+		"/us",
+		"/user/:id",
+	)
+	tests := []struct {
+		path    string
+		pattern string
+		params  Params
+	}{
+		// static beats :param beats *catchAll
+		{"/files/new", "/files/new", nil},
+		{"/files/x", "/files/:name", Params{{"name", "x"}}},
+		{"/files/x/y", "/files/*path", Params{{"path", "x/y"}}},
+		// backtracking from a partial static match
+		{"/files/news", "/files/:name", Params{{"name", "news"}}},
+		{"/files/new/raw", "/files/:name/raw", Params{{"name", "new"}}},
+		{"/files/a/b", "/files/*path", Params{{"path", "a/b"}}},
+		// constrained params before plain ones
+		{"/items/42", "/items/:id<int>", Params{{"id", "42"}}},
+		{"/items/abc", "/items/:slug", Params{{"slug", "abc"}}},
+		// params of a failed branch are dropped
+		{"/a/b/c", "/a/:x/c", Params{{"x", "b"}}},
+		{"/a/b/d", "/a/*rest", Params{{"rest", "b/d"}}},
+		// compressed prefixes
+		{"/", "/", nil},
+		{"/users", "/users", nil},
+		{"/us", "/us", nil},
+		{"/use", "", nil},
+		{"/user/5", "/user/:id", Params{{"id", "5"}}},
+		{"/user/", "", nil},
+		{"/nope", "", nil},
+	}
+	for _, tt := range tests {
+		var params Params
+		n := root.search(tt.path, &params)
+		got := ""
+		if n != nil {
+			got = n.pattern
+		}
+		if got != tt.pattern {
+			t.Errorf("%s: matched %q, want %q", tt.path, got, tt.pattern)
+			continue
+		}
+		if n != nil && !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("%s: params %v, want %v", tt.path, params, tt.params)
+		}
+	}
+}
+
+func TestNodeInsertConflict(t *testing.T) {
+	tests := []struct {
+		existing string
+		pattern  string
+	}{
+		{"/users/:id", "/users/:name"},
+		{"/users/:id<int>", "/users/:n<int>"},
+		{"/files/*path", "/files/*name"},
+		{"/users", "/users"},
+		{"/users/:id", "/users/:id"},
+	}
+	for _, tt := range tests {
+		root := newTree(t, tt.existing)
+		err := root.insert(tt.pattern, tt.pattern, nil)
+		if !errors.Is(err, ErrRouteConflict) {
+			t.Errorf("%s after %s: got %v, want ErrRouteConflict", tt.pattern, tt.existing, err)
+		}
+	}
+}
+
+func TestNodeInsertDistinctWildcards(t *testing.T) {
+	// different constraints and a catch-all may share a parent
+	newTree(t, "/users/:id<int>", "/users/:name", "/users/*rest")
+}
+
+func TestValidatePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{"/users/:id", true},
+		{"/users/:id<int>/posts", true},
+		{"/files/*path", true},
+		{"users", false},
+		{"/users/:", false},
+		{"/users/:id<int", false},
+		{"/users/a:id", false},
+		{"/files/*path/x", false},
+	}
+	for _, tt := range tests {
+		err := validatePattern(tt.pattern)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: got %v, want valid=%v", tt.pattern, err, tt.valid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidRoute) {
+			t.Errorf("%s: got %v, want ErrInvalidRoute", tt.pattern, err)
+		}
+	}
+}
+
+var benchRoutes = []string{
+	"/",
+	"/users",
+	"/users/list",
+	"/users/:id",
+	"/users/:id/posts/:post",
+	"/files/*path",
+	"/api/v1/items",
+	"/api/v1/items/:id",
+	"/api/v2/orders/:id",
+}
+
+// the segment trie used before the radix tree, kept as the baseline of the benchmarks
+type trieNode struct {
+	pattern  string
+	part     string
+	children []*trieNode
+	isWild   bool
+}
+
+func (n *trieNode) insert(pattern string, parts []string, height int) {
+	if len(parts) == height {
+		n.pattern = pattern
+		return
+	}
+	part := parts[height]
+	for _, child := range n.children {
+		if child.part == part {
+			child.insert(pattern, parts, height+1)
+			return
+		}
+	}
+	child := &trieNode{part: part, isWild: part[0] == ':' || part[0] == '*'}
+	n.children = append(n.children, child)
+	child.insert(pattern, parts, height+1)
+}
+
+func (n *trieNode) search(parts []string, height int) *trieNode {
+	if n.part != "" && n.part[0] == '*' {
+		return n
+	}
+	if len(parts) == height {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+	part := parts[height]
+	children := make([]*trieNode, 0)
+	for _, child := range n.children {
+		if child.part == part || child.isWild {
+			children = append(children, child)
+		}
+	}
+	for _, child := range children {
+		if result := child.search(parts, height+1); result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+type trieRouter struct {
+	roots    map[string]*trieNode
+	handlers map[string][]HandlerFunc
+}
+
+func trieParts(pattern string) []string {
+	parts := make([]string, 0)
+	for _, item := range strings.Split(pattern, "/") {
+		if item != "" {
+			parts = append(parts, item)
+			if item[0] == '*' {
+				break
+			}
+		}
+	}
+	return parts
+}
+
+func (r *trieRouter) addRoute(method string, pattern string) {
+	if _, ok := r.roots[method]; !ok {
+		r.roots[method] = &trieNode{}
+	}
+	r.roots[method].insert(pattern, trieParts(pattern), 0)
+	r.handlers[method+"-"+pattern] = nil
+}
+
+func (r *trieRouter) getRoute(method string, path string) (*trieNode, map[string]string) {
+	searchParts := trieParts(path)
+	params := make(map[string]string)
+	root, ok := r.roots[method]
+	if !ok {
+		return nil, nil
+	}
+	n := root.search(searchParts, 0)
+	if n == nil {
+		return nil, nil
+	}
+	for index, part := range trieParts(n.pattern) {
+		if part[0] == ':' {
+			params[part[1:]] = searchParts[index]
+		}
+		if part[0] == '*' && len(part) > 1 {
+			params[part[1:]] = strings.Join(searchParts[index:], "/")
+			break
+		}
+	}
+	_ = r.handlers[method+"-"+n.pattern]
+	return n, params
+}
+
+// radix tree against the old trie on the same routes
+func benchRouter(b *testing.B, path string) {
+	b.Run("radix", func(b *testing.B) {
+		r := newRouter()
+		for _, p := range benchRoutes {
+			if err := r.addRoute("GET", p); err != nil {
+				b.Fatal(err)
+			}
+		}
+		params := make(Params, 0, r.maxParams)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			params = params[:0]
+			if r.getRoute("GET", path, &params) == nil {
+				b.Fatalf("%s: no route", path)
+			}
+		}
+	})
+	b.Run("trie", func(b *testing.B) {
+		r := &trieRouter{roots: make(map[string]*trieNode), handlers: make(map[string][]HandlerFunc)}
+		for _, p := range benchRoutes {
+			r.addRoute("GET", p)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if n, _ := r.getRoute("GET", path); n == nil {
+				b.Fatalf("%s: no route", path)
+			}
+		}
+	})
+}
+
+func BenchmarkRouterStatic(b *testing.B)   { benchRouter(b, "/api/v1/items") }
+func BenchmarkRouterParam(b *testing.B)    { benchRouter(b, "/users/42/posts/7") }
+func BenchmarkRouterCatchAll(b *testing.B) { benchRouter(b, "/files/a/b/c.txt") }
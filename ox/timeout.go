@@ -0,0 +1,158 @@
+package ox
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// response of a handler running under Timeout, copied to the client only if it finishes in time
+type timeoutBuffer struct {
+	mu       sync.Mutex
+	header   http.Header
+	code     int
+	body     bytes.Buffer
+	timedOut bool
+	finished bool
+}
+
+// called by the handler goroutine once the chain returned, false if the timeout fired first
+func (tb *timeoutBuffer) finish() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.finished = !tb.timedOut
+	return tb.finished
+}
+
+// called when the deadline passed, false if the handlers finished first
+func (tb *timeoutBuffer) timeout() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.timedOut = !tb.finished
+	return tb.timedOut
+}
+
+func (tb *timeoutBuffer) Header() http.Header {
+	return tb.header
+}
+
+func (tb *timeoutBuffer) WriteHeader(code int) {
+	tb.mu.Lock()
+	if !tb.timedOut && tb.code == 0 {
+		tb.code = code
+	}
+	tb.mu.Unlock()
+}
+
+func (tb *timeoutBuffer) Write(data []byte) (int, error) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	if tb.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return tb.body.Write(data)
+}
+
+// middleware replying 503 once d has passed, for a group via Use or for a single route.
+// the handlers after it run on another goroutine with a copy of the Context writing into a buffer,
+// the response is sent when they return in time and discarded otherwise.
+// they should still watch c.Done() to stop work nobody waits for, and cannot hijack or flush the connection
+func Timeout(d time.Duration) HandlerFunc {
+	return func(c *Context) {
+		ctx, cancel := context.WithTimeout(c.Req.Context(), d)
+		defer cancel()
+		c.Req = c.Req.WithContext(ctx)
+
+		tb := &timeoutBuffer{header: make(http.Header)}
+		f := c.fork(tb)
+		// nil once the handlers returned, the panic if they panicked
+		result := make(chan *handlerPanic, 1)
+		go func() {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				hp := &handlerPanic{value: p, stack: debug.Stack()}
+				cleanupMultipart(f)
+				if tb.finish() {
+					result <- hp
+					return
+				}
+				f.Logger().ERROR().
+					Str("method", f.Method).
+					Str("path", f.Path).
+					Str("panic", fmt.Sprint(p)).
+					Str("stack", string(hp.stack)).
+					Msg("panic after timeout")
+			}()
+			f.Next()
+			if !tb.finish() {
+				cleanupMultipart(f) // nobody joins an abandoned fork
+				return
+			}
+			result <- nil
+		}()
+
+		select {
+		case hp := <-result:
+			c.join(f, tb, hp)
+		case <-ctx.Done():
+			if !tb.timeout() {
+				c.join(f, tb, <-result)
+				return
+			}
+			c.Abort()
+			if ctx.Err() == context.DeadlineExceeded {
+				c.Error(NewHTTPError(http.StatusServiceUnavailable, "503 SERVICE UNAVAILABLE: request timeout"))
+			}
+		}
+	}
+}
+
+// panic of a handler running under Timeout, raised again on the request goroutine
+// with the stack of the handler so Recovery logs where it happened
+type handlerPanic struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *handlerPanic) String() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+// take over the state and buffered response of a fork that finished the chain, or raise its panic
+func (c *Context) join(f *Context, tb *timeoutBuffer, hp *handlerPanic) {
+	if hp != nil {
+		panic(hp) // for the Recovery middleware before Timeout
+	}
+	if f.Req.Body == &f.bodymem {
+		c.bodymem = f.bodymem
+	}
+	if f.Req.MultipartForm != nil { // removed with the request by cleanupMultipart
+		c.Req.Form = f.Req.Form
+		c.Req.PostForm = f.Req.PostForm
+		c.Req.MultipartForm = f.Req.MultipartForm
+	}
+	c.index = f.index
+	c.StatusCode = f.StatusCode
+	c.errors = append(c.errors, f.errors...)
+	f.mu.RLock()
+	for k, v := range f.keys {
+		c.Set(k, v)
+	}
+	f.mu.RUnlock()
+	header := c.Writer.Header()
+	for k, v := range tb.header {
+		header[k] = v
+	}
+	c.Writer.WriteHeader(f.Writer.Status())
+	if f.Writer.Written() {
+		c.Writer.WriteHeaderNow()
+		_, _ = tb.body.WriteTo(c.Writer)
+	}
+}
@@ -0,0 +1,222 @@
+package ox
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	logger "ox-web/log"
+)
+
+// run with -race: the abandoned handler keeps reading its body while the Context is reused
+func TestTimeoutBodyLimit(t *testing.T) {
+	app := New()
+	app.MaxBodySize = 1 << 10
+	release := make(chan struct{})
+	finished := make(chan error, 1)
+	app.POST("/slow", Timeout(10*time.Millisecond), func(c *Context) {
+		<-release
+		_, err := c.Body()
+		finished <- err
+	})
+	app.POST("/limited", BodyLimit(4), Timeout(time.Second), func(c *Context) {
+		if _, err := c.Body(); err != nil {
+			c.Error(err)
+		}
+	})
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/slow", strings.NewReader("first")))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got %d, want 503", w.Code)
+	}
+	close(release)
+	// the pooled Context is reset by the next requests while the handler still reads
+	for i := 0; i < 10; i++ {
+		app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/limited", strings.NewReader("ok")))
+	}
+	if err := <-finished; err != nil {
+		t.Fatalf("abandoned handler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/limited", ioutil.NopCloser(strings.NewReader("too large")))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("got %d, want 413", w.Code)
+	}
+}
+
+func multipartBody(t *testing.T, size int) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = fw.Write(bytes.Repeat([]byte("x"), size))
+	_ = mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+// path of the temp file a spooled upload was written to
+func spooledFile(t *testing.T, c *Context) string {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		t.Error(err)
+		return ""
+	}
+	f, err := fh.Open()
+	if err != nil {
+		t.Error(err)
+		return ""
+	}
+	defer f.Close()
+	osf, ok := f.(*os.File)
+	if !ok {
+		t.Error("upload was not spooled to disk")
+		return ""
+	}
+	return osf.Name()
+}
+
+func TestTimeoutMultipartCleanup(t *testing.T) {
+	app := New()
+	app.MaxMultipartMemory = 16
+	app.Use(RequestID()) // keys exist before the fork
+	var tmp string
+	app.POST("/upload", Timeout(time.Second), func(c *Context) {
+		tmp = spooledFile(t, c)
+		c.Status(http.StatusNoContent)
+	})
+	abandoned := make(chan string, 1)
+	app.POST("/slow", Timeout(10*time.Millisecond), func(c *Context) {
+		time.Sleep(50 * time.Millisecond)
+		abandoned <- spooledFile(t, c)
+	})
+
+	body, ct := multipartBody(t, 1024)
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", ct)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || tmp == "" {
+		t.Fatalf("got %d", w.Code)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("temp file %s left after the request: %v", tmp, err)
+	}
+
+	body, ct = multipartBody(t, 1024)
+	req = httptest.NewRequest(http.MethodPost, "/slow", body)
+	req.Header.Set("Content-Type", ct)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("got %d, want 503", w.Code)
+	}
+	tmp = <-abandoned
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := os.Stat(tmp); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("temp file %s of the abandoned handler left", tmp)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	app := New()
+	app.GET("/fast", func(c *Context) { c.Set("before", 1) }, Timeout(time.Second), func(c *Context) {
+		c.Set("after", c.GetInt("before")+1)
+		c.SetHeader("X-Handler", "fast")
+		c.String(http.StatusCreated, "value %v", c.Req.Context().Value("after"))
+	})
+	app.GET("/status", Timeout(time.Second), func(c *Context) { c.Status(http.StatusAccepted) })
+	app.GET("/error", Timeout(time.Second), func(c *Context) { c.Error(NewHTTPError(http.StatusTeapot, "")) })
+	app.GET("/slow", Timeout(20*time.Millisecond), func(c *Context) {
+		time.Sleep(200 * time.Millisecond) // ignores c.Done()
+		c.String(http.StatusOK, "late")
+	})
+	var after interface{}
+	app.GET("/keys", func(c *Context) {
+		c.Next()
+		after, _ = c.Get("after")
+	}, Timeout(time.Second), func(c *Context) { c.Set("after", "set") })
+
+	tests := []struct {
+		path   string
+		code   int
+		body   string
+		header string
+	}{
+		{"/fast", http.StatusCreated, "value 2", "fast"},
+		{"/status", http.StatusAccepted, "", ""},
+		{"/error", http.StatusTeapot, "", ""},
+		{"/slow", http.StatusServiceUnavailable, "", ""},
+	}
+	for _, tt := range tests {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.path, w.Code, tt.code)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Errorf("%s: got body %q, want %q", tt.path, w.Body, tt.body)
+		}
+		if got := w.Header().Get("X-Handler"); got != tt.header {
+			t.Errorf("%s: got X-Handler %q, want %q", tt.path, got, tt.header)
+		}
+		if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+			t.Errorf("%s: replied after %s", tt.path, elapsed)
+		}
+	}
+	app.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/keys", nil))
+	if after != "set" {
+		t.Errorf("keys set under Timeout: got %v", after)
+	}
+}
+
+func handlerThatPanics(c *Context) {
+	panic("boom")
+}
+
+func TestTimeoutPanic(t *testing.T) {
+	var buf bytes.Buffer
+	app := New()
+	app.Use(func(c *Context) {
+		c.Req = c.Req.WithContext(logger.WithContext(c.Req.Context(), logger.New(&buf)))
+		c.Next()
+	}, Recovery())
+	app.GET("/panic", Timeout(time.Second), handlerThatPanics)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got %d, want 500", w.Code)
+	}
+	var entry struct {
+		Panic string `json:"panic"`
+		Stack string `json:"stack"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("%v: %s", err, buf.String())
+	}
+	if entry.Panic != "boom" {
+		t.Errorf("got panic %q, want boom", entry.Panic)
+	}
+	if !strings.Contains(entry.Stack, "handlerThatPanics") {
+		t.Errorf("stack misses the handler frame:\n%s", entry.Stack)
+	}
+}
@@ -0,0 +1,77 @@
+package ox
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// parsed multipart form, files beyond Application.MaxMultipartMemory are spooled to temp files
+// that are removed when the request ends. limit the upload size of a route with BodyLimit
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if c.Req.MultipartForm == nil {
+		if err := c.Req.ParseMultipartForm(c.app.MaxMultipartMemory); err != nil {
+			return nil, c.bodyError(err)
+		}
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// first file of the multipart form field name
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[name]
+	if len(files) == 0 {
+		return nil, fmt.Errorf("form file %s: %w", name, http.ErrMissingFile)
+	}
+	return files[0], nil
+}
+
+// content type of an uploaded file sniffed from its first 512 bytes, the header sent by the client is not trusted
+func DetectContentType(file *multipart.FileHeader) (string, error) {
+	f, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// copy an uploaded file to dst, missing directories are created
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// remove the temp files of a parsed multipart form
+func cleanupMultipart(c *Context) {
+	if form := c.Req.MultipartForm; form != nil {
+		_ = form.RemoveAll()
+	}
+}
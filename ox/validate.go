@@ -0,0 +1,395 @@
+package ox
+
+import (
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// checks field against the rule parameter, the part after '=' in the tag
+type ValidationFunc func(field reflect.Value, param string) bool
+
+// field failing a rule of its `validate` tag
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Param != "" {
+		return fmt.Sprintf("%s: failed on %s=%s", e.Field, e.Rule, e.Param)
+	}
+	return fmt.Sprintf("%s: failed on %s", e.Field, e.Rule)
+}
+
+// field errors of a struct, replied as 422 with the fields in the problem meta
+type ValidationErrors []*FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return "validation: " + strings.Join(msgs, "; ")
+}
+
+func (errs ValidationErrors) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]ValidationFunc{
+		"email":   isEmail,
+		"url":     isURL,
+		"uuid":    stringRule(isUUID),
+		"alpha":   stringRule(isAlpha),
+		"alnum":   stringRule(isAlnum),
+		"numeric": stringRule(isNumeric),
+		"oneof":   isOneOf,
+		"min":     compareRule(func(n, p float64) bool { return n >= p }),
+		"max":     compareRule(func(n, p float64) bool { return n <= p }),
+		"len":     compareRule(func(n, p float64) bool { return n == p }),
+		"eq":      compareRule(func(n, p float64) bool { return n == p }),
+		"ne":      compareRule(func(n, p float64) bool { return n != p }),
+		"gt":      compareRule(func(n, p float64) bool { return n > p }),
+		"gte":     compareRule(func(n, p float64) bool { return n >= p }),
+		"lt":      compareRule(func(n, p float64) bool { return n < p }),
+		"lte":     compareRule(func(n, p float64) bool { return n <= p }),
+	}
+	// parsed rules of struct types
+	rulesCache sync.Map
+)
+
+// add a rule usable in `validate` tags, replacing a built-in one of the same name
+func RegisterValidation(name string, fn ValidationFunc) error {
+	if name == "" || strings.ContainsAny(name, ",=") || isKeywordRule(name) {
+		return fmt.Errorf("validation: invalid rule name %q", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("validation: rule %s: nil func", name)
+	}
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[name] = fn
+	// rules are resolved when a type is first parsed
+	rulesCache.Range(func(key, _ interface{}) bool {
+		rulesCache.Delete(key)
+		return true
+	})
+	return nil
+}
+
+func isKeywordRule(name string) bool {
+	return name == "required" || name == "omitempty" || name == "dive" || name == "-"
+}
+
+type rule struct {
+	name  string
+	param string
+	fn    ValidationFunc
+}
+
+type fieldRules struct {
+	index     int
+	name      string
+	required  bool
+	omitempty bool
+	rules     []rule
+	dive      []rule // applied to the elements of a slice or map
+}
+
+// fields of a struct type with a `validate` tag or holding structs to walk into
+func structRules(t reflect.Type) ([]fieldRules, error) {
+	if cached, ok := rulesCache.Load(t); ok {
+		return cached.([]fieldRules), nil
+	}
+	var fields []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		fr := fieldRules{index: i, name: fieldName(sf)}
+		if err := parseRules(&fr, tag); err != nil {
+			return nil, fmt.Errorf("validation: %s.%s: %w", t, sf.Name, err)
+		}
+		if tag == "" && !hasStructs(sf.Type) {
+			continue
+		}
+		fields = append(fields, fr)
+	}
+	rulesCache.Store(t, fields)
+	return fields, nil
+}
+
+func parseRules(fr *fieldRules, tag string) error {
+	if tag == "" {
+		return nil
+	}
+	dive := false
+	for _, part := range strings.Split(tag, ",") {
+		name, param := part, ""
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			name, param = part[:i], part[i+1:]
+		}
+		switch name {
+		case "required":
+			fr.required = true
+			continue
+		case "omitempty":
+			fr.omitempty = true
+			continue
+		case "dive":
+			dive = true
+			continue
+		}
+		validatorsMu.RLock()
+		fn, ok := validators[name]
+		validatorsMu.RUnlock()
+		if !ok {
+			return fmt.Errorf("unknown rule %q", name)
+		}
+		if dive {
+			fr.dive = append(fr.dive, rule{name: name, param: param, fn: fn})
+		} else {
+			fr.rules = append(fr.rules, rule{name: name, param: param, fn: fn})
+		}
+	}
+	return nil
+}
+
+// name of the field in the request, taken from the binding tags
+func fieldName(sf reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "uri", "header", "xml"} {
+		name := sf.Tag.Get(tag)
+		if i := strings.IndexByte(name, ','); i >= 0 {
+			name = name[:i]
+		}
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func hasStructs(t reflect.Type) bool {
+	t = derefType(t)
+	if t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = derefType(t.Elem())
+	}
+	return t.Kind() == reflect.Struct && !isScalar(t)
+}
+
+// check obj against the `validate` tags of its fields, nested structs included.
+// failures are ValidationErrors, a malformed tag is a plain error
+func Validate(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	var errs ValidationErrors
+	if err := validateValue(v, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// walk into structs and the structs held by slices and maps
+func validateValue(v reflect.Value, ns string, errs *ValidationErrors) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if isScalar(v.Type()) {
+			return nil
+		}
+		return validateStruct(v, ns, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := validateValue(v.Index(i), ns+"["+strconv.Itoa(i)+"]", errs); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := validateValue(iter.Value(), fmt.Sprintf("%s[%v]", ns, iter.Key()), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, ns string, errs *ValidationErrors) error {
+	fields, err := structRules(v.Type())
+	if err != nil {
+		return err
+	}
+	for _, fr := range fields {
+		fv := v.Field(fr.index)
+		name := fr.name
+		if v.Type().Field(fr.index).Anonymous {
+			name = ns // embedded fields belong to the outer struct
+		} else if ns != "" {
+			name = ns + "." + name
+		}
+		if isEmpty(fv) {
+			if fr.required {
+				*errs = append(*errs, &FieldError{Field: name, Rule: "required"})
+			}
+			if fr.required || fr.omitempty || fv.Kind() == reflect.Ptr {
+				continue
+			}
+		}
+		if !checkRules(fv, fr.rules, name, errs) {
+			continue
+		}
+		if len(fr.dive) > 0 {
+			diveRules(fv, fr.dive, name, errs)
+		}
+		if err := validateValue(fv, name, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// false if a rule failed
+func checkRules(v reflect.Value, rules []rule, name string, errs *ValidationErrors) bool {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	for _, r := range rules {
+		if !r.fn(v, r.param) {
+			*errs = append(*errs, &FieldError{Field: name, Rule: r.name, Param: r.param})
+			return false
+		}
+	}
+	return true
+}
+
+func diveRules(v reflect.Value, rules []rule, name string, errs *ValidationErrors) {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			checkRules(v.Index(i), rules, name+"["+strconv.Itoa(i)+"]", errs)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			checkRules(iter.Value(), rules, fmt.Sprintf("%s[%v]", name, iter.Key()), errs)
+		}
+	}
+}
+
+// nil, zero or without elements
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func stringRule(fn func(string) bool) ValidationFunc {
+	return func(v reflect.Value, _ string) bool {
+		return v.Kind() == reflect.String && fn(v.String())
+	}
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func isEmail(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	addr, err := mail.ParseAddress(v.String())
+	return err == nil && addr.Address == v.String()
+}
+
+func isURL(v reflect.Value, _ string) bool {
+	if v.Kind() != reflect.String {
+		return false
+	}
+	u, err := url.ParseRequestURI(v.String())
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// oneof=red green blue
+func isOneOf(v reflect.Value, param string) bool {
+	var s string
+	switch v.Kind() {
+	case reflect.String:
+		s = v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(v.Uint(), 10)
+	default:
+		return false
+	}
+	for _, option := range strings.Fields(param) {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
+
+// compares numbers by value, strings, slices and maps by length and durations parsed from param
+func compareRule(cmp func(n, p float64) bool) ValidationFunc {
+	return func(v reflect.Value, param string) bool {
+		var n float64
+		switch v.Kind() {
+		case reflect.String:
+			n = float64(utf8.RuneCountInString(v.String()))
+		case reflect.Slice, reflect.Array, reflect.Map:
+			n = float64(v.Len())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if v.Type() == durationType {
+				d, err := time.ParseDuration(param)
+				return err == nil && cmp(float64(v.Int()), float64(d))
+			}
+			n = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		default:
+			return false
+		}
+		p, err := strconv.ParseFloat(param, 64)
+		return err == nil && cmp(n, p)
+	}
+}
@@ -0,0 +1,215 @@
+package ox
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRules(t *testing.T) {
+	nick := "ab"
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string // failing fields as field:rule
+	}{
+		{"required string", &struct {
+			Name string `json:"name" validate:"required"`
+		}{}, "name:required"},
+		{"required pointer", &struct {
+			Nick *string `validate:"required"`
+		}{}, "Nick:required"},
+		{"required slice", &struct {
+			Tags []string `form:"tag" validate:"required"`
+		}{Tags: []string{}}, "tag:required"},
+		{"required ok", &struct {
+			Name string `validate:"required"`
+		}{Name: "x"}, ""},
+		{"min string counts runes", &struct {
+			Name string `validate:"min=3"`
+		}{Name: "éé"}, "Name:min"},
+		{"max string", &struct {
+			Name string `validate:"max=3"`
+		}{Name: "abcd"}, "Name:max"},
+		{"len slice", &struct {
+			IDs []int `validate:"len=2"`
+		}{IDs: []int{1}}, "IDs:len"},
+		{"gte lte", &struct {
+			Age  int     `validate:"gte=18"`
+			Rate float64 `validate:"lte=1"`
+		}{Age: 17, Rate: 1.5}, "Age:gte Rate:lte"},
+		{"gt lt ok", &struct {
+			N uint `validate:"gt=1,lt=3"`
+		}{N: 2}, ""},
+		{"duration", &struct {
+			Wait time.Duration `validate:"max=1s"`
+		}{Wait: 2 * time.Second}, "Wait:max"},
+		{"email", &struct {
+			Good string `validate:"email"`
+			Bad  string `validate:"email"`
+			Name string `validate:"email"`
+		}{Good: "a@b.co", Bad: "nope", Name: "A <a@b.co>"}, "Bad:email Name:email"},
+		{"url", &struct {
+			Good string `validate:"url"`
+			Bad  string `validate:"url"`
+		}{Good: "https://x.io/a", Bad: "/a"}, "Bad:url"},
+		{"oneof", &struct {
+			Color string `validate:"oneof=red green"`
+			Level int    `validate:"oneof=1 2"`
+		}{Color: "blue", Level: 2}, "Color:oneof"},
+		{"uuid alpha alnum numeric", &struct {
+			ID  string `validate:"uuid"`
+			A   string `validate:"alpha"`
+			AN  string `validate:"alnum"`
+			Num string `validate:"numeric"`
+		}{ID: "123e4567-e89b-12d3-a456-426614174000", A: "a1", AN: "a1", Num: "1.5"}, "A:alpha"},
+		{"omitempty", &struct {
+			Site string `validate:"omitempty,url"`
+		}{}, ""},
+		{"omitempty pointer", &struct {
+			Nick *string `validate:"omitempty,min=3"`
+		}{Nick: &nick}, "Nick:min"},
+		{"nil pointer without rules", &struct {
+			Nick *string `validate:"min=3"`
+		}{}, ""},
+		{"first failing rule only", &struct {
+			Name string `validate:"min=5,alpha"`
+		}{Name: "1"}, "Name:min"},
+		{"dive", &struct {
+			Tags []string `json:"tags" validate:"max=3,dive,min=2"`
+		}{Tags: []string{"a", "bb", "c"}}, "tags[0]:min tags[2]:min"},
+		{"dive map", &struct {
+			Meta map[string]string `validate:"dive,alpha"`
+		}{Meta: map[string]string{"k": "1"}}, "Meta[k]:alpha"},
+		{"skipped", &struct {
+			Name string `validate:"-"`
+		}{}, ""},
+	}
+	for _, tt := range tests {
+		got := fieldErrors(t, Validate(tt.value))
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateEmbedded struct {
+	Code string `json:"code" validate:"len=2"`
+}
+
+type validateUser struct {
+	validateEmbedded
+	Home    validateAddress            `json:"home"`
+	Work    *validateAddress           `json:"work"`
+	Old     []validateAddress          `json:"old"`
+	ByName  map[string]validateAddress `json:"by_name"`
+	Missing *validateAddress           `json:"missing"`
+}
+
+func TestValidateNested(t *testing.T) {
+	u := validateUser{
+		validateEmbedded: validateEmbedded{Code: "x"},
+		Work:             &validateAddress{},
+		Old:              []validateAddress{{City: "a"}, {}},
+		ByName:           map[string]validateAddress{"k": {}},
+	}
+	want := "code:len home.city:required work.city:required old[1].city:required by_name[k].city:required"
+	if got := fieldErrors(t, Validate(&u)); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	err := Validate(&struct {
+		Name string `validate:"nope"`
+	}{})
+	var errs ValidationErrors
+	if err == nil || errors.As(err, &errs) {
+		t.Errorf("got %v, want a plain error", err)
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	type flagged struct {
+		Name string `validate:"test_flag"`
+	}
+	pass := func(reflect.Value, string) bool { return true }
+	fail := func(reflect.Value, string) bool { return false }
+	if err := RegisterValidation("test_flag", pass); err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(&flagged{}); err != nil {
+		t.Fatalf("got %v", err)
+	}
+	// the rules of flagged are cached now, registering again must replace them
+	if err := RegisterValidation("test_flag", fail); err != nil {
+		t.Fatal(err)
+	}
+	if got := fieldErrors(t, Validate(&flagged{})); got != "Name:test_flag" {
+		t.Errorf("got %q", got)
+	}
+	for _, name := range []string{"", "a,b", "a=b", "required", "dive"} {
+		if err := RegisterValidation(name, pass); err == nil {
+			t.Errorf("%q: got no error", name)
+		}
+	}
+	if err := RegisterValidation("test_nil", nil); err == nil {
+		t.Error("nil func: got no error")
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	app := New()
+	app.POST("/users", func(c *Context) {
+		var v struct {
+			Name  string `json:"name" validate:"required"`
+			Email string `json:"email" validate:"required,email"`
+		}
+		if err := c.BindJSON(&v); err != nil {
+			c.Error(err)
+		}
+	})
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"email":"x"}`))
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	var problem struct {
+		Status int          `json:"status"`
+		Meta   []FieldError `json:"meta"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnprocessableEntity || problem.Status != http.StatusUnprocessableEntity {
+		t.Errorf("got %d", w.Code)
+	}
+	want := []FieldError{{Field: "name", Rule: "required"}, {Field: "email", Rule: "email"}}
+	if !reflect.DeepEqual(problem.Meta, want) {
+		t.Errorf("got meta %+v", problem.Meta)
+	}
+}
+
+// failing fields of a ValidationErrors as "field:rule ..."
+func fieldErrors(t *testing.T, err error) string {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want ValidationErrors", err)
+	}
+	fields := make([]string, len(errs))
+	for i, e := range errs {
+		fields[i] = e.Field + ":" + e.Rule
+	}
+	return strings.Join(fields, " ")
+}
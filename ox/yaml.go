@@ -0,0 +1,173 @@
+package ox
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// yaml object keeping the key order of the json it was read from
+type yamlMap struct {
+	keys   []string
+	values []interface{}
+}
+
+// encode v as a block style yaml document.
+// v is marshaled to json first, so json tags and json.Marshaler apply
+func encodeYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tree, err := readYAMLValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writeYAML(&buf, tree, 0, false)
+	return buf.Bytes(), nil
+}
+
+func readYAMLValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		m := &yamlMap{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readYAMLValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			m.keys = append(m.keys, key.(string))
+			m.values = append(m.values, value)
+		}
+		_, err = dec.Token() // }
+		return m, err
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := readYAMLValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token() // ]
+		return list, err
+	}
+	return tok, nil
+}
+
+// write v at indent. inline is set for values following "- " on the current line
+func writeYAML(w *bytes.Buffer, v interface{}, indent int, inline bool) {
+	pad := strings.Repeat("  ", indent)
+	switch v := v.(type) {
+	case *yamlMap:
+		if len(v.keys) == 0 {
+			w.WriteString("{}\n")
+			return
+		}
+		for i, key := range v.keys {
+			if i > 0 || !inline {
+				w.WriteString(pad)
+			}
+			w.WriteString(yamlScalar(key))
+			w.WriteByte(':')
+			writeYAMLChild(w, v.values[i], indent)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			w.WriteString("[]\n")
+			return
+		}
+		for i, item := range v {
+			if i > 0 || !inline {
+				w.WriteString(pad)
+			}
+			w.WriteString("- ")
+			writeYAML(w, item, indent+1, true)
+		}
+	default:
+		w.WriteString(yamlScalar(v))
+		w.WriteByte('\n')
+	}
+}
+
+// value of a key, collections start on the next line
+func writeYAMLChild(w *bytes.Buffer, v interface{}, indent int) {
+	if isYAMLCollection(v) {
+		w.WriteByte('\n')
+		writeYAML(w, v, indent+1, false)
+		return
+	}
+	w.WriteByte(' ')
+	writeYAML(w, v, indent+1, true)
+}
+
+// non-empty map or list
+func isYAMLCollection(v interface{}) bool {
+	switch v := v.(type) {
+	case *yamlMap:
+		return len(v.keys) > 0
+	case []interface{}:
+		return len(v) > 0
+	}
+	return false
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		if yamlPlain(v) {
+			return v
+		}
+		// json strings are valid double-quoted yaml scalars
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		_ = encoder.Encode(v)
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+	return ""
+}
+
+// whether s can be written unquoted without being read back as another type.
+// strings starting with a digit, '.' or '-' are quoted as they may read as numbers, dates or markers
+func yamlPlain(s string) bool {
+	if s == "" || s[len(s)-1] == ' ' {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "y", "n", "on", "off", "null":
+		return false
+	}
+	if c := s[0] | 0x20; (c < 'a' || c > 'z') && s[0] != '_' && s[0] != '/' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == ' ' || c == '_' || c == '-' || c == '.' || c == '/' || c == '(' || c == ')' || c == '+':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package ox
+
+import (
+	"testing"
+)
+
+type yamlItem struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+func TestEncodeYAML(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"plain", "hello world", "hello world\n"},
+		{"bool words", []string{"yes", "No", "on", "y", "null", "true"},
+			"- \"yes\"\n- \"No\"\n- \"on\"\n- \"y\"\n- \"null\"\n- \"true\"\n"},
+		{"number like", []string{"1.0", "-x", ".5", "2020-01-02"},
+			"- \"1.0\"\n- \"-x\"\n- \".5\"\n- \"2020-01-02\"\n"},
+		{"empty and spaces", []string{"", "a ", " a"}, "- \"\"\n- \"a \"\n- \" a\"\n"},
+		{"indicators", []string{"a: b", "a #b", "[x]", "*x", "a\nb"},
+			"- \"a: b\"\n- \"a #b\"\n- \"[x]\"\n- \"*x\"\n- \"a\\nb\"\n"},
+		{"scalars", []interface{}{1, 1.5, true, nil}, "- 1\n- 1.5\n- true\n- null\n"},
+		{"empty collections", map[string]interface{}{"a": []int{}, "b": map[string]int{}},
+			"a: []\nb: {}\n"},
+		{"struct keeps field order", yamlItem{Name: "x", Tags: []string{"a", "b"}},
+			"name: x\ntags:\n  - a\n  - b\n"},
+		{"list of maps", []yamlItem{{Name: "x"}, {Name: "y", Tags: []string{"a"}}},
+			"- name: x\n  tags: null\n- name: \"y\"\n  tags:\n    - a\n"},
+		{"nested lists", [][]int{{1, 2}, {3}}, "- - 1\n  - 2\n- - 3\n"},
+		{"nested maps", map[string]interface{}{"a": map[string]interface{}{"b": map[string]int{"c": 1}}},
+			"a:\n  b:\n    c: 1\n"},
+	}
+	for _, tt := range tests {
+		data, err := encodeYAML(tt.value)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := string(data); got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}